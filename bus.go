@@ -0,0 +1,106 @@
+package lightswarm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Returned when writing to a closed Bus
+var ErrBusClosed = errors.New("lightswarm: bus closed")
+
+// A queued write waiting for the bus
+type busWrite struct {
+	ctx    context.Context
+	b      []byte
+	result chan busResult
+}
+
+// The result of a queued write
+type busResult struct {
+	n   int
+	err error
+}
+
+// Owns a single shared writer, such as a serial port, and serialises
+// writes to it so that frames from LED's driven by many goroutines are
+// never interleaved. The Bus is itself an io.Writer, each call to Write
+// is written to the underlying writer in full before the next begins.
+type Bus struct {
+	writer io.Writer
+	queue  chan busWrite
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Writes queued frames to the underlying writer until the bus is closed
+func (bus *Bus) run() {
+	for {
+		select {
+		case w := <-bus.queue:
+			n, err := WriteContext(w.ctx, bus.writer, w.b)
+			w.result <- busResult{n, err}
+		case <-bus.done:
+			return
+		}
+	}
+}
+
+// Queue the bytes for writing, blocking until they have been written
+func (bus *Bus) Write(p []byte) (int, error) {
+	return bus.WriteContext(context.Background(), p)
+}
+
+// Queue the bytes for writing, blocking until they have been written or
+// the context is done. If the context is done while the bytes are still
+// queued they are dropped, once the write has started it will complete.
+func (bus *Bus) WriteContext(ctx context.Context, p []byte) (int, error) {
+	w := busWrite{
+		ctx:    ctx,
+		b:      append([]byte{}, p...),
+		result: make(chan busResult, 1),
+	}
+	select {
+	case bus.queue <- w:
+	case <-bus.done:
+		return 0, ErrBusClosed
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	select {
+	case r := <-w.result:
+		return r.n, r.err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// Returns an LED handle that writes to the bus
+func (bus *Bus) LED(addr uint16) *LED {
+	return New(addr, bus)
+}
+
+// Returns a Group handle that writes to the bus
+func (bus *Bus) Group(addr uint16) *Group {
+	return NewGroup(addr, bus)
+}
+
+// Stop accepting writes, the underlying writer is not closed
+func (bus *Bus) Close() error {
+	bus.once.Do(func() {
+		close(bus.done)
+	})
+	return nil
+}
+
+// Constructs a new Bus writing to the given writer
+func NewBus(writer io.Writer) *Bus {
+	bus := &Bus{
+		writer: writer,
+		queue:  make(chan busWrite),
+		done:   make(chan struct{}),
+	}
+	go bus.run()
+	return bus
+}
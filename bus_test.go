@@ -0,0 +1,134 @@
+package lightswarm
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A writer that writes one byte at a time, yielding between each, to
+// expose any interleaving of concurrent writes
+type slowWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		w.mu.Lock()
+		w.buf.WriteByte(b)
+		w.mu.Unlock()
+		runtime.Gosched()
+	}
+	return len(p), nil
+}
+
+// A writer that always fails
+type errWriter struct {
+	err error
+}
+
+func (w errWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestBusLED(t *testing.T) {
+	tt := []struct {
+		name     string
+		addr     uint16
+		buff     *bytes.Buffer
+		expected []byte
+		n        int
+		err      error
+	}{
+		{
+			"turn 690 on",
+			690,
+			bytes.NewBuffer(nil),
+			[]byte{END, 2, 178, ON, 144, END},
+			6,
+			nil,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			bus := NewBus(tc.buff)
+			defer bus.Close()
+			led := bus.LED(tc.addr)
+			assert.Equal(t, tc.addr, led.Addr)
+			n, b, err := led.On()
+			t.Logf("%s bytes: %v", tc.name, b)
+			assert.Equal(t, tc.n, n)
+			assert.Equal(t, tc.err, err)
+			bs, err := ioutil.ReadAll(tc.buff)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, bs)
+		})
+	}
+}
+
+func TestBusGroup(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	bus := NewBus(buff)
+	defer bus.Close()
+	group := bus.Group(4096)
+	assert.Equal(t, uint16(4096), group.Addr)
+	_, _, err := group.On()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{END, 16, 0, ON, 48, END}, buff.Bytes())
+}
+
+func TestBusWriteError(t *testing.T) {
+	boom := errors.New("boom")
+	bus := NewBus(errWriter{boom})
+	defer bus.Close()
+	n, b, err := bus.LED(690).On()
+	assert.Equal(t, 0, n)
+	assert.Nil(t, b)
+	assert.Equal(t, boom, err)
+}
+
+func TestBusClose(t *testing.T) {
+	bus := NewBus(ioutil.Discard)
+	assert.Nil(t, bus.Close())
+	assert.Nil(t, bus.Close())
+	_, err := bus.Write([]byte{END})
+	assert.Equal(t, ErrBusClosed, err)
+}
+
+func TestBusConcurrentFrameAtomicity(t *testing.T) {
+	w := &slowWriter{}
+	bus := NewBus(w)
+	defer bus.Close()
+	const leds, frames = 10, 20
+	var wg sync.WaitGroup
+	for i := 0; i < leds; i++ {
+		wg.Add(1)
+		go func(led *LED) {
+			defer wg.Done()
+			for j := 0; j < frames; j++ {
+				_, _, err := led.FadeRGB(Fade{j, 1, 1}, Fade{j, 1, 1}, Fade{j, 1, 1})
+				assert.Nil(t, err)
+			}
+		}(bus.LED(uint16(690 + i)))
+	}
+	wg.Wait()
+	r := NewReader(&w.buf)
+	count := 0
+	for {
+		f, err := r.ReadFrame()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, FADE_RGB_TO_LEVEL, f.Cmd)
+		count++
+	}
+	assert.Equal(t, leds*frames, count)
+}
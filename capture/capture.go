@@ -0,0 +1,141 @@
+/*
+Package capture records the bytes written to a LightSwarm bus with
+timestamps so they can be inspected or replayed later.
+
+A Writer tees everything written through it to a capture file, so it can be
+placed in front of the serial port to record what the lights were actually
+sent:
+
+	f, _ := os.Create("show.lscap")
+	w, _ := capture.NewWriter(serialPort, f)
+	led := lightswarm.New(690, w)
+
+The capture can be played back at its original speed, scaled or as fast as
+possible with a Replayer, or exported as pcapng for Wireshark:
+
+	r, _ := capture.NewReader(f)
+	capture.NewReplayer(serialPort).Replay(ctx, r)
+
+The file starts with an 8 byte magic and version followed by the wall
+clock time capture started in nanoseconds since the Unix epoch. Each write
+is then stored as a record of its offset from the start in nanoseconds,
+measured on the monotonic clock, its length and the bytes written. Writes
+longer than 174,080 bytes are split into several records.
+
+	"LSCAP\x00\x00\x01" | start int64 | (offset int64 | length uint32 | data)...
+
+All integers are big endian.
+*/
+package capture
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/thisissoon/lightswarm"
+)
+
+// File magic including the format version
+const magic = "LSCAP\x00\x00\x01"
+
+// Sizes of the file header and record headers
+const (
+	headerSize       = len(magic) + 8
+	recordHeaderSize = 12
+)
+
+// Maximum length of a record, enough for 1024 frames with every byte
+// escaped. Longer writes are split into several records with the same
+// offset, and Reader rejects longer records as corrupt rather than
+// allocating whatever length the file claims.
+const maxRecordLen = (2*lightswarm.MaxFrameLen + 2) * 1024
+
+// Returned when reading a file which is not a capture
+var ErrInvalidCapture = errors.New("capture: invalid capture file")
+
+// Writes the file header for a capture started at the given time
+func writeHeader(w io.Writer, start time.Time) error {
+	b := make([]byte, headerSize)
+	copy(b, magic)
+	binary.BigEndian.PutUint64(b[len(magic):], uint64(start.UnixNano()))
+	_, err := w.Write(b)
+	return err
+}
+
+// Encodes a record
+func appendRecord(b []byte, offset time.Duration, data []byte) []byte {
+	var h [recordHeaderSize]byte
+	binary.BigEndian.PutUint64(h[:8], uint64(offset))
+	binary.BigEndian.PutUint32(h[8:], uint32(len(data)))
+	return append(append(b, h[:]...), data...)
+}
+
+// Forwards writes to Writer, recording the bytes written to the capture.
+// A failing capture never fails the write, the first capture error is
+// kept and returned by Err and later writes are no longer recorded.
+type Writer struct {
+	// Exported Fields
+	Writer io.Writer
+
+	mu      sync.Mutex
+	capture io.Writer
+	start   time.Time
+	err     error
+
+	// Overridden in tests
+	now func() time.Time
+}
+
+// Records the bytes written at the current time
+func (w *Writer) record(p []byte) {
+	if w.err != nil || len(p) == 0 {
+		return
+	}
+	offset := w.now().Sub(w.start)
+	b := []byte{}
+	for len(p) > maxRecordLen {
+		b = appendRecord(b, offset, p[:maxRecordLen])
+		p = p[maxRecordLen:]
+	}
+	_, w.err = w.capture.Write(appendRecord(b, offset, p))
+}
+
+// Writes to the underlying writer and records the bytes written
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.WriteContext(context.Background(), p)
+}
+
+// As Write but abandoned if the context is done before it is written
+func (w *Writer) WriteContext(ctx context.Context, p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n, err := lightswarm.WriteContext(ctx, w.Writer, p)
+	w.record(p[:n])
+	return n, err
+}
+
+// Returns the first error writing to the capture
+func (w *Writer) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Constructs a new Writer forwarding to the given writer and recording to
+// capture, the file header is written immediately
+func NewWriter(writer, capture io.Writer) (*Writer, error) {
+	w := &Writer{
+		Writer:  writer,
+		capture: capture,
+		now:     time.Now,
+	}
+	w.start = w.now()
+	if err := writeHeader(capture, w.start); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
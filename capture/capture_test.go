@@ -0,0 +1,146 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thisissoon/lightswarm"
+)
+
+// A controllable clock, sleeping advances it
+type fakeClock struct {
+	t     time.Time
+	slept []time.Duration
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func (c *fakeClock) sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.slept = append(c.slept, d)
+	c.advance(d)
+	return nil
+}
+
+// A writer that always fails
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("boom")
+}
+
+// Returns a Writer recording to a buffer with a fake clock
+func fakeWriter(w *bytes.Buffer) (*Writer, *fakeClock, *bytes.Buffer) {
+	clock := &fakeClock{t: time.Unix(1500000000, 0)}
+	capture := &bytes.Buffer{}
+	writeHeader(capture, clock.t)
+	return &Writer{
+		Writer:  w,
+		capture: capture,
+		start:   clock.t,
+		now:     clock.now,
+	}, clock, capture
+}
+
+func TestNewWriter(t *testing.T) {
+	capture := &bytes.Buffer{}
+	w, err := NewWriter(&bytes.Buffer{}, capture)
+	assert.NoError(t, err)
+	assert.Equal(t, headerSize, capture.Len())
+	assert.Equal(t, magic, capture.String()[:len(magic)])
+	r, err := NewReader(capture)
+	assert.NoError(t, err)
+	assert.Equal(t, w.start.UnixNano(), r.Start.UnixNano())
+}
+
+func TestNewWriterHeaderError(t *testing.T) {
+	_, err := NewWriter(&bytes.Buffer{}, errWriter{})
+	assert.EqualError(t, err, "boom")
+}
+
+func TestWriterWrite(t *testing.T) {
+	out := &bytes.Buffer{}
+	w, clock, capture := fakeWriter(out)
+	on := lightswarm.Frame{Addr: 690, Cmd: lightswarm.ON}.Bytes()
+	off := lightswarm.Frame{Addr: 690, Cmd: lightswarm.OFF}.Bytes()
+	clock.advance(time.Millisecond * 5)
+	n, err := w.Write(on)
+	assert.NoError(t, err)
+	assert.Equal(t, len(on), n)
+	clock.advance(time.Second)
+	w.Write(off)
+	assert.Equal(t, append(append([]byte{}, on...), off...), out.Bytes())
+	r, err := NewReader(capture)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1500000000, 0), r.Start)
+	rec, err := r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, Record{Offset: time.Millisecond * 5, Data: on}, rec)
+	assert.Equal(t, time.Unix(1500000000, int64(time.Millisecond*5)), r.Time(rec))
+	rec, err = r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, Record{Offset: time.Millisecond * 1005, Data: off}, rec)
+}
+
+func TestWriterWriteSplitsLongWrites(t *testing.T) {
+	w, _, capture := fakeWriter(&bytes.Buffer{})
+	long := bytes.Repeat([]byte{lightswarm.END}, maxRecordLen*2+1)
+	w.Write(long)
+	r, err := NewReader(capture)
+	assert.NoError(t, err)
+	lens := []int{}
+	for {
+		rec, err := r.Next()
+		if err != nil {
+			assert.Equal(t, io.EOF, err)
+			break
+		}
+		lens = append(lens, len(rec.Data))
+	}
+	assert.Equal(t, []int{maxRecordLen, maxRecordLen, 1}, lens)
+}
+
+func TestWriterWriteError(t *testing.T) {
+	clock := &fakeClock{}
+	capture := &bytes.Buffer{}
+	w := &Writer{Writer: errWriter{}, capture: capture, now: clock.now}
+	_, err := w.Write([]byte{0xC0})
+	assert.EqualError(t, err, "boom")
+	assert.Equal(t, 0, capture.Len())
+}
+
+func TestWriterCaptureError(t *testing.T) {
+	out := &bytes.Buffer{}
+	clock := &fakeClock{}
+	w := &Writer{Writer: out, capture: errWriter{}, now: clock.now}
+	n, err := w.Write([]byte{0xC0})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.EqualError(t, w.Err(), "boom")
+	w.Write([]byte{0xC0})
+	assert.Equal(t, []byte{0xC0, 0xC0}, out.Bytes())
+}
+
+func TestWriterWriteContext(t *testing.T) {
+	out := &bytes.Buffer{}
+	w, _, capture := fakeWriter(out)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := w.WriteContext(ctx, []byte{0xC0})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 0, out.Len())
+	assert.Equal(t, headerSize, capture.Len())
+}
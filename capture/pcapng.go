@@ -0,0 +1,91 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// LINKTYPE_USER0, the first link type reserved for private use. Wireshark
+// can be told to decode it with a Lua dissector or as raw data.
+const LinkTypeUser0 = 147
+
+// pcapng block types
+const (
+	blockSectionHeader     = 0x0A0D0D0A
+	blockInterface         = 0x00000001
+	blockEnhancedPacket    = 0x00000006
+	byteOrderMagic         = 0x1A2B3C4D
+	optionEnd              = 0
+	optionInterfaceTSResol = 9
+)
+
+// Encodes a pcapng block from the little endian encoding of its fields,
+// padding the body to 32 bits
+func block(typ uint32, fields ...interface{}) []byte {
+	body := &bytes.Buffer{}
+	for _, f := range fields {
+		binary.Write(body, binary.LittleEndian, f)
+	}
+	for body.Len()%4 != 0 {
+		body.WriteByte(0)
+	}
+	length := uint32(body.Len() + 12)
+	b := &bytes.Buffer{}
+	binary.Write(b, binary.LittleEndian, [2]uint32{typ, length})
+	b.Write(body.Bytes())
+	binary.Write(b, binary.LittleEndian, length)
+	return b.Bytes()
+}
+
+// Returns the section header block
+func sectionHeader() []byte {
+	return block(blockSectionHeader,
+		uint32(byteOrderMagic),
+		uint16(1), uint16(0), // version 1.0
+		int64(-1), // section length not specified
+	)
+}
+
+// Returns the interface description block, timestamps are in nanoseconds
+func interfaceDescription(linkType uint16) []byte {
+	return block(blockInterface,
+		linkType,
+		uint16(0), // reserved
+		uint32(0), // no snap length
+		uint16(optionInterfaceTSResol), uint16(1), []byte{9, 0, 0, 0},
+		uint16(optionEnd), uint16(0),
+	)
+}
+
+// Returns an enhanced packet block for the data at the given time in
+// nanoseconds since the Unix epoch
+func enhancedPacket(ts uint64, data []byte) []byte {
+	return block(blockEnhancedPacket,
+		uint32(0), // interface id
+		uint32(ts>>32), uint32(ts),
+		uint32(len(data)), uint32(len(data)),
+		data,
+	)
+}
+
+// Exports the capture as a pcapng file with a single interface of the
+// given link type, usually LinkTypeUser0, returning the number of packets
+// written. Each record becomes one packet stamped with its wall clock time.
+func WritePcapng(w io.Writer, r *Reader, linkType uint16) (int, error) {
+	if _, err := w.Write(append(sectionHeader(), interfaceDescription(linkType)...)); err != nil {
+		return 0, err
+	}
+	for i := 0; ; i++ {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return i, nil
+		}
+		if err != nil {
+			return i, err
+		}
+		if _, err := w.Write(enhancedPacket(uint64(r.Time(rec).UnixNano()), rec.Data)); err != nil {
+			return i, err
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A decoded pcapng block
+type pcapngBlock struct {
+	Type uint32
+	Body []byte
+}
+
+// Splits a pcapng file into blocks, checking the lengths match
+func pcapngBlocks(t *testing.T, b []byte) []pcapngBlock {
+	blocks := []pcapngBlock{}
+	for len(b) > 0 {
+		typ := binary.LittleEndian.Uint32(b[0:])
+		length := binary.LittleEndian.Uint32(b[4:])
+		assert.Equal(t, uint32(0), length%4)
+		assert.Equal(t, length, binary.LittleEndian.Uint32(b[length-4:]))
+		blocks = append(blocks, pcapngBlock{typ, b[8 : length-4]})
+		b = b[length:]
+	}
+	return blocks
+}
+
+func TestWritePcapng(t *testing.T) {
+	start := time.Unix(1500000000, 0)
+	data := captureFile(start,
+		Record{Offset: time.Millisecond, Data: []byte{0xC0, 2, 178, 0x20, 144, 0xC0}},
+		Record{Offset: time.Second, Data: []byte{0xC0}},
+	)
+	r, _ := NewReader(bytes.NewReader(data))
+	out := &bytes.Buffer{}
+	n, err := WritePcapng(out, r, LinkTypeUser0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	blocks := pcapngBlocks(t, out.Bytes())
+	assert.Len(t, blocks, 4)
+	assert.Equal(t, pcapngBlock{
+		Type: 0x0A0D0D0A,
+		Body: []byte{
+			0x4D, 0x3C, 0x2B, 0x1A,
+			1, 0, 0, 0,
+			0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+		},
+	}, blocks[0])
+	assert.Equal(t, pcapngBlock{
+		Type: 1,
+		Body: []byte{
+			147, 0, 0, 0,
+			0, 0, 0, 0,
+			9, 0, 1, 0, 9, 0, 0, 0,
+			0, 0, 0, 0,
+		},
+	}, blocks[1])
+	ts := uint64(start.Add(time.Millisecond).UnixNano())
+	packet := &bytes.Buffer{}
+	binary.Write(packet, binary.LittleEndian, []uint32{0, uint32(ts >> 32), uint32(ts), 6, 6})
+	packet.Write([]byte{0xC0, 2, 178, 0x20, 144, 0xC0, 0, 0})
+	assert.Equal(t, pcapngBlock{Type: 6, Body: packet.Bytes()}, blocks[2])
+	assert.Equal(t, uint32(1), binary.LittleEndian.Uint32(blocks[3].Body[12:]))
+	assert.Equal(t, byte(0xC0), blocks[3].Body[20])
+}
+
+func TestWritePcapngError(t *testing.T) {
+	r, _ := NewReader(bytes.NewReader(captureFile(time.Unix(1, 0))))
+	_, err := WritePcapng(errWriter{}, r, LinkTypeUser0)
+	assert.EqualError(t, err, "boom")
+}
@@ -0,0 +1,145 @@
+package capture
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/thisissoon/lightswarm"
+)
+
+// A single recorded write
+type Record struct {
+	Offset time.Duration // Time since the capture started
+	Data   []byte
+}
+
+// Reads records from a capture file
+type Reader struct {
+	// Exported Fields
+	Start time.Time // Wall clock time the capture started
+
+	r *bufio.Reader
+}
+
+// Returns the wall clock time of the record
+func (r *Reader) Time(rec Record) time.Time {
+	return r.Start.Add(rec.Offset)
+}
+
+// Reads the next record, io.EOF is returned at the end of the capture,
+// io.ErrUnexpectedEOF if it ends part way through a record and
+// ErrInvalidCapture if the record is longer than a Writer records
+func (r *Reader) Next() (Record, error) {
+	var h [recordHeaderSize]byte
+	if _, err := io.ReadFull(r.r, h[:]); err != nil {
+		return Record{}, err
+	}
+	n := binary.BigEndian.Uint32(h[8:])
+	if n > maxRecordLen {
+		return Record{}, ErrInvalidCapture
+	}
+	rec := Record{
+		Offset: time.Duration(binary.BigEndian.Uint64(h[:8])),
+		Data:   make([]byte, n),
+	}
+	if _, err := io.ReadFull(r.r, rec.Data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return Record{}, err
+	}
+	return rec, nil
+}
+
+// Constructs a new Reader, reading the file header from the given reader
+func NewReader(reader io.Reader) (*Reader, error) {
+	r := &Reader{
+		r: bufio.NewReader(reader),
+	}
+	h := make([]byte, headerSize)
+	if _, err := io.ReadFull(r.r, h); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrInvalidCapture
+		}
+		return nil, err
+	}
+	if string(h[:len(magic)]) != magic {
+		return nil, ErrInvalidCapture
+	}
+	r.Start = time.Unix(0, int64(binary.BigEndian.Uint64(h[len(magic):])))
+	return r, nil
+}
+
+// Re-emits the records of a capture to Writer, preserving the time
+// between them
+type Replayer struct {
+	// Exported Fields
+	Writer io.Writer
+	Speed  float64 // Playback speed, 1 is the original speed, 0 is as fast as possible
+
+	once sync.Once
+
+	// Overridden in tests
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+// Defaults the clock on first use, so a Replayer built as a literal is
+// usable
+func (p *Replayer) init() {
+	p.once.Do(func() {
+		if p.now == nil {
+			p.now = time.Now
+		}
+		if p.sleep == nil {
+			p.sleep = lightswarm.SleepContext
+		}
+	})
+}
+
+// Replays the capture from the first record until it ends, returning the
+// number of records written. Replay stops early if the context is done or
+// a write fails.
+func (p *Replayer) Replay(ctx context.Context, r *Reader) (int, error) {
+	p.init()
+	var (
+		first  time.Duration
+		origin time.Time
+	)
+	for i := 0; ; i++ {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return i, nil
+		}
+		if err != nil {
+			return i, err
+		}
+		if i == 0 {
+			first, origin = rec.Offset, p.now()
+		}
+		if p.Speed > 0 {
+			at := origin.Add(time.Duration(float64(rec.Offset-first) / p.Speed))
+			if d := at.Sub(p.now()); d > 0 {
+				if err := p.sleep(ctx, d); err != nil {
+					return i, err
+				}
+			}
+		}
+		if _, err := lightswarm.WriteContext(ctx, p.Writer, rec.Data); err != nil {
+			return i, err
+		}
+	}
+}
+
+// Constructs a new Replayer writing to the given writer at the original
+// speed
+func NewReplayer(writer io.Writer) *Replayer {
+	return &Replayer{
+		Writer: writer,
+		Speed:  1,
+	}
+}
@@ -0,0 +1,223 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a capture file containing the records
+func captureFile(start time.Time, recs ...Record) []byte {
+	b := &bytes.Buffer{}
+	writeHeader(b, start)
+	for _, rec := range recs {
+		b.Write(appendRecord(nil, rec.Offset, rec.Data))
+	}
+	return b.Bytes()
+}
+
+// Returns a Replayer with a fake clock
+func fakeReplayer(w io.Writer, speed float64) (*Replayer, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1600000000, 0)}
+	return &Replayer{
+		Writer: w,
+		Speed:  speed,
+		now:    clock.now,
+		sleep:  clock.sleep,
+	}, clock
+}
+
+func TestNewReader(t *testing.T) {
+	tt := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"empty", nil, ErrInvalidCapture},
+		{"short header", []byte(magic), ErrInvalidCapture},
+		{"bad magic", append([]byte("LSCAP\x00\x00\x02"), make([]byte, 8)...), ErrInvalidCapture},
+		{"valid", captureFile(time.Unix(1, 0)), nil},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewReader(bytes.NewReader(tc.data))
+			assert.Equal(t, tc.err, err)
+		})
+	}
+}
+
+func TestReaderNext(t *testing.T) {
+	data := captureFile(time.Unix(1, 0),
+		Record{Offset: time.Second, Data: []byte{1, 2}},
+		Record{Offset: time.Second * 2, Data: []byte{3}},
+	)
+	tt := []struct {
+		name     string
+		data     []byte
+		expected []Record
+		err      error
+	}{
+		{
+			"no records",
+			data[:headerSize],
+			[]Record{},
+			io.EOF,
+		},
+		{
+			"records",
+			data,
+			[]Record{
+				{Offset: time.Second, Data: []byte{1, 2}},
+				{Offset: time.Second * 2, Data: []byte{3}},
+			},
+			io.EOF,
+		},
+		{
+			"truncated record header",
+			data[:len(data)-5],
+			[]Record{{Offset: time.Second, Data: []byte{1, 2}}},
+			io.ErrUnexpectedEOF,
+		},
+		{
+			"truncated record data",
+			data[:len(data)-1],
+			[]Record{{Offset: time.Second, Data: []byte{1, 2}}},
+			io.ErrUnexpectedEOF,
+		},
+		{
+			"record too long",
+			append(data[:headerSize:headerSize], 0, 0, 0, 0, 0, 0, 0, 0, 0xFF, 0xFF, 0xFF, 0xFF),
+			[]Record{},
+			ErrInvalidCapture,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(tc.data))
+			assert.NoError(t, err)
+			recs := []Record{}
+			for {
+				rec, err := r.Next()
+				if err != nil {
+					assert.Equal(t, tc.err, err)
+					break
+				}
+				recs = append(recs, rec)
+			}
+			assert.Equal(t, tc.expected, recs)
+		})
+	}
+}
+
+func TestReplayerZeroValue(t *testing.T) {
+	out := &bytes.Buffer{}
+	data := captureFile(time.Unix(1, 0), Record{Data: []byte{1}}, Record{Offset: time.Millisecond, Data: []byte{2}})
+	r, err := NewReader(bytes.NewReader(data))
+	assert.NoError(t, err)
+	p := &Replayer{Writer: out, Speed: 1}
+	n, err := p.Replay(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []byte{1, 2}, out.Bytes())
+}
+
+func TestReplayerReplay(t *testing.T) {
+	data := captureFile(time.Unix(1, 0),
+		Record{Offset: time.Second, Data: []byte{1}},
+		Record{Offset: time.Second * 3, Data: []byte{2}},
+		Record{Offset: time.Second * 4, Data: []byte{3}},
+	)
+	tt := []struct {
+		name  string
+		speed float64
+		slept []time.Duration
+	}{
+		{"original speed", 1, []time.Duration{time.Second * 2, time.Second}},
+		{"double speed", 2, []time.Duration{time.Second, time.Millisecond * 500}},
+		{"half speed", 0.5, []time.Duration{time.Second * 4, time.Second * 2}},
+		{"max speed", 0, nil},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r, _ := NewReader(bytes.NewReader(data))
+			out := &bytes.Buffer{}
+			p, clock := fakeReplayer(out, tc.speed)
+			n, err := p.Replay(context.Background(), r)
+			assert.NoError(t, err)
+			assert.Equal(t, 3, n)
+			assert.Equal(t, []byte{1, 2, 3}, out.Bytes())
+			assert.Equal(t, tc.slept, clock.slept)
+		})
+	}
+}
+
+func TestReplayerReplayCatchesUp(t *testing.T) {
+	data := captureFile(time.Unix(1, 0),
+		Record{Offset: 0, Data: []byte{1}},
+		Record{Offset: time.Second, Data: []byte{2}},
+		Record{Offset: time.Second * 2, Data: []byte{3}},
+	)
+	r, _ := NewReader(bytes.NewReader(data))
+	clock := &fakeClock{}
+	p := &Replayer{
+		Writer: writerFunc(func(b []byte) (int, error) {
+			if b[0] == 1 {
+				clock.advance(time.Millisecond * 1500) // slow write
+			}
+			return len(b), nil
+		}),
+		Speed: 1,
+		now:   clock.now,
+		sleep: clock.sleep,
+	}
+	n, err := p.Replay(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []time.Duration{time.Millisecond * 500}, clock.slept)
+}
+
+func TestReplayerReplayErrors(t *testing.T) {
+	data := captureFile(time.Unix(1, 0),
+		Record{Offset: 0, Data: []byte{1}},
+		Record{Offset: time.Second, Data: []byte{2}},
+	)
+	t.Run("write error", func(t *testing.T) {
+		r, _ := NewReader(bytes.NewReader(data))
+		p, _ := fakeReplayer(errWriter{}, 1)
+		n, err := p.Replay(context.Background(), r)
+		assert.EqualError(t, err, "boom")
+		assert.Equal(t, 0, n)
+	})
+	t.Run("context done", func(t *testing.T) {
+		r, _ := NewReader(bytes.NewReader(data))
+		out := &bytes.Buffer{}
+		p, _ := fakeReplayer(out, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		p.Writer = writerFunc(func(b []byte) (int, error) {
+			cancel()
+			return out.Write(b)
+		})
+		n, err := p.Replay(ctx, r)
+		assert.Equal(t, context.Canceled, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, []byte{1}, out.Bytes())
+	})
+	t.Run("truncated", func(t *testing.T) {
+		r, _ := NewReader(bytes.NewReader(data[:len(data)-1]))
+		p, _ := fakeReplayer(&bytes.Buffer{}, 0)
+		n, err := p.Replay(context.Background(), r)
+		assert.Equal(t, io.ErrUnexpectedEOF, err)
+		assert.Equal(t, 1, n)
+	})
+}
+
+// Adapts a function to io.Writer
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,229 @@
+/*
+Command lightswarmctl sends single commands to LightSwarm LED's and
+decodes the frames on the bus.
+
+	Usage: lightswarmctl [flags] <command> <address> [arguments]
+	       lightswarmctl [flags] decode [hex]...
+	       lightswarmctl [flags] sniff
+
+	Commands:
+	  on       <address>
+	  off      <address>
+	  toggle   <address>
+	  level    <address> <level>
+	  fade     <address> <level> <interval> <step>
+	  rgb      <address> <red> <green> <blue>
+	  fade-rgb <address> <red> <interval> <step> <green> <interval> <step> <blue> <interval> <step>
+	  decode   [hex]...  print the frames in hex encoded bytes, read from stdin if none are given
+	  sniff              print the frames read from the device as they arrive
+
+	Flags:
+	  -device  serial device or file to write to, or read from when sniffing, - for stdout or stdin (default "-")
+	  -dry-run print the frame bytes as hex instead of writing them
+
+The serial device is opened for writing as a plain file, so it must already
+be configured for the LightSwarm baud rate, for example:
+
+	stty -F /dev/ttyUSB0 38400 raw
+	lightswarmctl -device /dev/ttyUSB0 fade 690 255 1 5
+
+Decoded frames are printed one per line with their checksum verified,
+fade arguments are shown as (level,interval,step):
+
+	lightswarmctl decode c002b22090c0
+	690 ON chk=OK
+	lightswarmctl -device /dev/ttyUSB0 sniff
+	690 FADE_RGB_TO_LEVEL r=(255,1,1) g=(0,1,1) b=(0,1,1) chk=OK
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/thisissoon/lightswarm"
+)
+
+// Usage error
+var errUsage = errors.New("usage: lightswarmctl [flags] <command> <address> [arguments]")
+
+// Parses a LightSwarm address
+func parseAddr(s string) (uint16, error) {
+	addr, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", s)
+	}
+	return uint16(addr), nil
+}
+
+// Parses command arguments, each must fit in a byte
+func parseArgs(names, args []string) ([]byte, error) {
+	if len(args) != len(names) {
+		return nil, fmt.Errorf("expected %d arguments, got %d", len(names), len(args))
+	}
+	bs := make([]byte, len(args))
+	for i, arg := range args {
+		b, err := strconv.ParseUint(arg, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q, must be 0-255", names[i], arg)
+		}
+		bs[i] = byte(b)
+	}
+	return bs, nil
+}
+
+// Opens the device for writing, - is stdout
+func open(device string, stdout io.Writer) (io.WriteCloser, error) {
+	if device == "-" {
+		return nopCloser{stdout}, nil
+	}
+	return os.OpenFile(device, os.O_WRONLY, 0)
+}
+
+// Wraps a writer with a no-op Close
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// Prints a line describing each frame in the stream as it is read, noise
+// too long to be a frame is reported and skipped up to the next END byte
+func describe(r io.Reader, stdout io.Writer) error {
+	reader := lightswarm.NewReader(r)
+	for {
+		raw, err := reader.ReadRaw()
+		if err == io.EOF {
+			return nil
+		}
+		if ferr, ok := err.(*lightswarm.FrameError); ok {
+			fmt.Fprintf(stdout, "invalid frame % x...: %s\n", ferr.Raw, ferr.Err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, lightswarm.Describe(raw))
+	}
+}
+
+// Decodes hex, ignoring whitespace and colons between bytes
+func decodeHex(s string) ([]byte, error) {
+	s = strings.Map(func(r rune) rune {
+		if r == ':' || r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, s)
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("decode: invalid hex: %s", err)
+	}
+	return b, nil
+}
+
+// Prints the frames in the hex arguments, or hex read from stdin
+func decode(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		in, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		args = []string{string(in)}
+	}
+	for _, arg := range args {
+		b, err := decodeHex(arg)
+		if err != nil {
+			return err
+		}
+		if err := describe(bytes.NewReader(b), stdout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prints the frames read from the device, - is stdin
+func sniff(device string, stdin io.Reader, stdout io.Writer) error {
+	r := stdin
+	if device != "-" {
+		f, err := os.Open(device)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	return describe(r, stdout)
+}
+
+// Runs lightswarmctl with the given arguments
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("lightswarmctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	device := flags.String("device", "-", "serial device or file to write to, or read from when sniffing, - for stdout or stdin")
+	dryRun := flags.Bool("dry-run", false, "print the frame bytes as hex instead of writing them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	switch flags.Arg(0) {
+	case "decode":
+		return decode(flags.Args()[1:], stdin, stdout)
+	case "sniff":
+		return sniff(*device, stdin, stdout)
+	}
+	if flags.NArg() < 2 {
+		return errUsage
+	}
+	name, rest := flags.Arg(0), flags.Args()[2:]
+	named, ok := lightswarm.LookupCommand(name)
+	if !ok {
+		return fmt.Errorf("unknown command %q", name)
+	}
+	addr, err := parseAddr(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	cmdArgs, err := parseArgs(named.Args, rest)
+	if err != nil {
+		return fmt.Errorf("%s: %s", name, err)
+	}
+	cmd, err := named.Command(cmdArgs)
+	if err != nil {
+		return fmt.Errorf("%s: %s", name, err)
+	}
+	var w io.WriteCloser = nopCloser{ioutil.Discard}
+	if !*dryRun {
+		if w, err = open(*device, stdout); err != nil {
+			return err
+		}
+	}
+	defer w.Close()
+	_, b, err := lightswarm.New(addr, w).Send(cmd)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Fprintf(stdout, "% x\n", b)
+	}
+	return nil
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(2)
+	}
+}
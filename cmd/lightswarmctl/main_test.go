@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunDryRun(t *testing.T) {
+	tt := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			"on",
+			[]string{"-dry-run", "on", "690"},
+			"c0 02 b2 20 90 c0\n",
+		},
+		{
+			"off",
+			[]string{"-dry-run", "off", "690"},
+			"c0 02 b2 21 91 c0\n",
+		},
+		{
+			"toggle",
+			[]string{"-dry-run", "toggle", "690"},
+			"c0 02 b2 2d 9d c0\n",
+		},
+		{
+			"level",
+			[]string{"-dry-run", "level", "690", "128"},
+			"c0 02 b2 22 80 12 c0\n",
+		},
+		{
+			"fade",
+			[]string{"-dry-run", "fade", "690", "255", "1", "1"},
+			"c0 02 b2 23 ff 01 01 6c c0\n",
+		},
+		{
+			"rgb",
+			[]string{"-dry-run", "rgb", "690", "85", "199", "237"},
+			"c0 02 b2 2c 55 c7 ed e3 c0\n",
+		},
+		{
+			"fade-rgb",
+			[]string{"-dry-run", "fade-rgb", "690", "85", "1", "1", "199", "1", "1", "237", "1", "1"},
+			"c0 02 b2 31 55 01 01 c7 01 01 ed 01 01 fe c0\n",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := bytes.NewBuffer(nil)
+			err := run(tc.args, nil, stdout, ioutil.Discard)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, stdout.String())
+		})
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tt := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			"no arguments",
+			[]string{},
+			errUsage.Error(),
+		},
+		{
+			"unknown command",
+			[]string{"explode", "690"},
+			`unknown command "explode"`,
+		},
+		{
+			"invalid address",
+			[]string{"on", "65536"},
+			`invalid address "65536"`,
+		},
+		{
+			"missing arguments",
+			[]string{"level", "690"},
+			"level: expected 1 arguments, got 0",
+		},
+		{
+			"invalid argument",
+			[]string{"rgb", "690", "1", "256", "1"},
+			`rgb: invalid green "256", must be 0-255`,
+		},
+		{
+			"missing device",
+			[]string{"-device", "/does/not/exist", "on", "690"},
+			"open /does/not/exist: no such file or directory",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := run(tc.args, nil, ioutil.Discard, ioutil.Discard)
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
+
+func TestRunStdout(t *testing.T) {
+	stdout := bytes.NewBuffer(nil)
+	err := run([]string{"on", "690"}, nil, stdout, ioutil.Discard)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xC0, 2, 178, 0x20, 144, 0xC0}, stdout.Bytes())
+}
+
+func TestRunDevice(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lightswarmctl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	device := filepath.Join(dir, "tty")
+	assert.Nil(t, ioutil.WriteFile(device, nil, 0644))
+	err = run([]string{"-device", device, "off", "690"}, nil, ioutil.Discard, ioutil.Discard)
+	assert.Nil(t, err)
+	bs, err := ioutil.ReadFile(device)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xC0, 2, 178, 0x21, 145, 0xC0}, bs)
+}
+
+func TestRunDecode(t *testing.T) {
+	tt := []struct {
+		name     string
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{
+			"argument",
+			[]string{"decode", "c002b22090c0"},
+			"",
+			"690 ON chk=OK\n",
+		},
+		{
+			"several frames and arguments",
+			[]string{"decode", "c0 02 b2 20 90 c0 c0 02 b2 22 80 12 c0", "c0:02:b2:21:90:c0"},
+			"",
+			"690 ON chk=OK\n690 SET_LEVEL level=128 chk=OK\n690 OFF chk=BAD(got 0x90 want 0x91)\n",
+		},
+		{
+			"stdin",
+			[]string{"decode"},
+			"c002b231ff0101000101000101 7e c0\nc002b22c55c7ede3c0\n",
+			"690 FADE_RGB_TO_LEVEL r=(255,1,1) g=(0,1,1) b=(0,1,1) chk=OK\n690 SET_RGB_LEVELS r=85 g=199 b=237 chk=OK\n",
+		},
+		{
+			"garbage before frame",
+			[]string{"decode", "02c002b22090c0"},
+			"",
+			"invalid frame 02: lightswarm: truncated frame\n690 ON chk=OK\n",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout := bytes.NewBuffer(nil)
+			err := run(tc.args, strings.NewReader(tc.stdin), stdout, ioutil.Discard)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, stdout.String())
+		})
+	}
+}
+
+func TestRunDecodeInvalidHex(t *testing.T) {
+	err := run([]string{"decode", "c0zz"}, nil, ioutil.Discard, ioutil.Discard)
+	assert.EqualError(t, err, "decode: invalid hex: encoding/hex: invalid byte: U+007A 'z'")
+}
+
+func TestRunSniff(t *testing.T) {
+	stream := []byte{0xC0, 2, 178, 0x20, 144, 0xC0, 0xC0, 2, 178, 0x2C, 85, 199, 237, 0xE3, 0xC0}
+	t.Run("stdin", func(t *testing.T) {
+		stdout := bytes.NewBuffer(nil)
+		err := run([]string{"sniff"}, bytes.NewReader(stream), stdout, ioutil.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, "690 ON chk=OK\n690 SET_RGB_LEVELS r=85 g=199 b=237 chk=OK\n", stdout.String())
+	})
+	t.Run("device", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "lightswarmctl")
+		assert.Nil(t, err)
+		defer os.RemoveAll(dir)
+		device := filepath.Join(dir, "tty")
+		assert.Nil(t, ioutil.WriteFile(device, stream[:6], 0644))
+		stdout := bytes.NewBuffer(nil)
+		err = run([]string{"-device", device, "sniff"}, nil, stdout, ioutil.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, "690 ON chk=OK\n", stdout.String())
+	})
+	t.Run("noise without an END byte", func(t *testing.T) {
+		noise := append([]byte{0xC0}, bytes.Repeat([]byte{0xFF}, 100000)...)
+		stdout := bytes.NewBuffer(nil)
+		err := run([]string{"sniff"}, bytes.NewReader(append(noise, stream...)), stdout, ioutil.Discard)
+		assert.Nil(t, err)
+		lines := strings.Split(stdout.String(), "\n")
+		assert.Len(t, lines, 4)
+		assert.True(t, strings.HasPrefix(lines[0], "invalid frame ff ff"))
+		assert.True(t, strings.HasSuffix(lines[0], "...: lightswarm: frame too long"))
+		assert.Equal(t, []string{"690 ON chk=OK", "690 SET_RGB_LEVELS r=85 g=199 b=237 chk=OK", ""}, lines[1:])
+	})
+	t.Run("missing device", func(t *testing.T) {
+		err := run([]string{"-device", "/does/not/exist", "sniff"}, nil, ioutil.Discard, ioutil.Discard)
+		assert.EqualError(t, err, "open /does/not/exist: no such file or directory")
+	})
+}
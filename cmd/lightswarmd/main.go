@@ -0,0 +1,89 @@
+/*
+Command lightswarmd serves the httpapi REST API for controlling LightSwarm
+LED's over HTTP.
+
+	Usage: lightswarmd [flags]
+
+	Flags:
+	  -listen  address to listen on (default ":8080")
+	  -device  serial device or file to write to, - for stdout (default "-")
+	  -baud    serial baud rate used to pace writes (default 38400)
+	  -capture file to record everything written to the device to
+
+The serial device is opened for writing as a plain file, so it must already
+be configured for the LightSwarm baud rate, for example:
+
+	stty -F /dev/ttyUSB0 38400 raw
+	lightswarmd -device /dev/ttyUSB0
+	curl -X PUT -d '{"on": true}' http://localhost:8080/leds/690
+
+A capture records the bytes sent to the device with timestamps, so what
+the lights were sent can be inspected or replayed with the capture package.
+An existing capture file is overwritten.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/thisissoon/lightswarm"
+	"github.com/thisissoon/lightswarm/capture"
+	"github.com/thisissoon/lightswarm/httpapi"
+)
+
+// Builds the API handler writing to the given device. Writes are paced
+// for the baud rate, tracked for GET requests and serialised on a bus.
+func handler(device io.Writer, baud int) (http.Handler, *lightswarm.Bus) {
+	tracker := lightswarm.NewTracker(lightswarm.NewPacer(device, baud, 0))
+	bus := lightswarm.NewBus(tracker)
+	s := httpapi.New(bus)
+	s.Tracker = tracker
+	return s, bus
+}
+
+// Opens the device for writing, - is stdout
+func open(device string) (io.Writer, error) {
+	if device == "-" {
+		return os.Stdout, nil
+	}
+	return os.OpenFile(device, os.O_WRONLY, 0)
+}
+
+// Wraps the device to record everything written to it in the capture file
+func record(device io.Writer, path string) (io.Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := capture.NewWriter(device, f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func main() {
+	listen := flag.String("listen", ":8080", "address to listen on")
+	device := flag.String("device", "-", "serial device or file to write to, - for stdout")
+	baud := flag.Int("baud", lightswarm.DefaultBaud, "serial baud rate used to pace writes")
+	capturePath := flag.String("capture", "", "file to record everything written to the device to")
+	flag.Parse()
+	w, err := open(*device)
+	if err == nil && *capturePath != "" {
+		w, err = record(w, *capturePath)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	h, bus := handler(w, *baud)
+	defer bus.Close()
+	log.Printf("listening on %s", *listen)
+	log.Fatal(http.ListenAndServe(*listen, h))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thisissoon/lightswarm/capture"
+)
+
+func TestHandler(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	h, bus := handler(buff, 38400)
+	defer bus.Close()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/leds/690", strings.NewReader(`{"on": true}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []byte{0xC0, 2, 178, 0x20, 144, 0xC0}, buff.Bytes())
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/leds/690", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"on":true,"level":0,"rgb":[0,0,0]}`, w.Body.String())
+}
+
+func TestRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lightswarmd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.lscap")
+	buff := bytes.NewBuffer(nil)
+	w, err := record(buff, path)
+	assert.NoError(t, err)
+	frame := []byte{0xC0, 2, 178, 0x20, 144, 0xC0}
+	w.Write(frame)
+	assert.Equal(t, frame, buff.Bytes())
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	r, err := capture.NewReader(f)
+	assert.NoError(t, err)
+	rec, err := r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, frame, rec.Data)
+}
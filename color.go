@@ -0,0 +1,384 @@
+package lightswarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Returned when parsing an invalid colour
+var ErrInvalidColor = errors.New("lightswarm: invalid colour")
+
+// A Red, Green and Blue colour
+type Color struct {
+	R, G, B byte
+}
+
+// Constructs a colour from Red, Green and Blue levels
+func RGB(r, g, b byte) Color {
+	return Color{r, g, b}
+}
+
+// Rounds a level from 0 to 1 to a byte, clamping values outside the range
+func level(f float64) byte {
+	return byte(math.Floor(clamp(f, 0, 1)*255 + 0.5))
+}
+
+// Returns f limited to min and max
+func clamp(f, min, max float64) float64 {
+	return math.Max(min, math.Min(max, f))
+}
+
+// Constructs a colour from chroma, hue and the amount added to each channel
+// to match lightness or value
+func chroma(c, h, m float64) Color {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	h /= 60
+	x := c * (1 - math.Abs(math.Mod(h, 2)-1))
+	var r, g, b float64
+	switch int(h) {
+	case 0:
+		r, g = c, x
+	case 1:
+		r, g = x, c
+	case 2:
+		g, b = c, x
+	case 3:
+		g, b = x, c
+	case 4:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	return Color{level(r + m), level(g + m), level(b + m)}
+}
+
+// Constructs a colour from hue in degrees, saturation and value from 0 to 1
+func HSV(h, s, v float64) Color {
+	s, v = clamp(s, 0, 1), clamp(v, 0, 1)
+	c := v * s
+	return chroma(c, h, v-c)
+}
+
+// Constructs a colour from hue in degrees, saturation and lightness from
+// 0 to 1
+func HSL(h, s, l float64) Color {
+	s, l = clamp(s, 0, 1), clamp(l, 0, 1)
+	c := (1 - math.Abs(2*l-1)) * s
+	return chroma(c, h, l-c/2)
+}
+
+// Parses a #rrggbb or #rgb hex colour, the # is optional
+func Hex(s string) (Color, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return Color{}, ErrInvalidColor
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return Color{}, ErrInvalidColor
+	}
+	return Color{byte(v >> 16), byte(v >> 8), byte(v)}, nil
+}
+
+// Returns the CSS named colour, names are case insensitive
+func Name(name string) (Color, error) {
+	c, ok := cssColors[strings.ToLower(name)]
+	if !ok {
+		return Color{}, ErrInvalidColor
+	}
+	return c, nil
+}
+
+// Parses a hex colour or CSS colour name
+func ParseColor(s string) (Color, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "#") {
+		return Hex(s)
+	}
+	if c, err := Name(s); err == nil {
+		return c, nil
+	}
+	return Hex(s)
+}
+
+// Constructs the colour of a black body at the correlated colour
+// temperature in Kelvin, from 1000K to 40000K. Candlelight is around 1900K,
+// incandescent bulbs 2700K and daylight 6500K.
+func Kelvin(k float64) Color {
+	t := clamp(k, 1000, 40000) / 100
+	var r, g, b float64
+	if t <= 66 {
+		r = 255
+		g = 99.4708025861*math.Log(t) - 161.1195681661
+	} else {
+		r = 329.698727446 * math.Pow(t-60, -0.1332047592)
+		g = 288.1221695283 * math.Pow(t-60, -0.0755148492)
+	}
+	switch {
+	case t >= 66:
+		b = 255
+	case t <= 19:
+		b = 0
+	default:
+		b = 138.5177312231*math.Log(t-10) - 305.0447927307
+	}
+	return Color{level(r / 255), level(g / 255), level(b / 255)}
+}
+
+// Returns the colour as a #rrggbb hex string
+func (c Color) String() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Returns the colour as a #rrggbb hex string
+func (c Color) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// Parses a hex colour or CSS colour name
+func (c *Color) UnmarshalText(text []byte) error {
+	parsed, err := ParseColor(string(text))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// Per channel lookup tables correcting colours for the response of an LED
+// before they are sent
+type Calibration [3][256]byte
+
+// Constructs a calibration applying the gamma of each channel, 0 is treated
+// as 1 (linear), then scaling each channel so full white becomes the white
+// point
+func NewCalibration(gamma [3]float64, white Color) *Calibration {
+	cal := &Calibration{}
+	max := [3]byte{white.R, white.G, white.B}
+	for ch := range cal {
+		g := gamma[ch]
+		if g <= 0 {
+			g = 1
+		}
+		for v := range cal[ch] {
+			f := math.Pow(float64(v)/255, g) * float64(max[ch]) / 255
+			cal[ch][v] = level(f)
+		}
+	}
+	return cal
+}
+
+// Returns the corrected colour, a nil calibration returns the colour
+// unchanged
+func (cal *Calibration) Apply(c Color) Color {
+	if cal == nil {
+		return c
+	}
+	return Color{cal[0][c.R], cal[1][c.G], cal[2][c.B]}
+}
+
+// A light whose colours are corrected by Cal before they are sent, every
+// other command including SetRGB and FadeRGB is passed through unchanged
+type Calibrated struct {
+	ContextLight
+	Cal *Calibration // May be nil to send colours unchanged
+}
+
+// Set the corrected colour
+func (l Calibrated) SetColor(c Color) (int, []byte, error) {
+	return l.SetColorContext(context.Background(), c)
+}
+
+// As SetColor but abandoned if the context is done before it is written
+func (l Calibrated) SetColorContext(ctx context.Context, c Color) (int, []byte, error) {
+	c = l.Cal.Apply(c)
+	return l.SetRGBContext(ctx, c.R, c.G, c.B)
+}
+
+// Fade to the corrected colour with the same timing on every channel
+func (l Calibrated) FadeColor(c Color, t Timing) (int, []byte, error) {
+	return l.FadeColorContext(context.Background(), c, t)
+}
+
+// As FadeColor but abandoned if the context is done before it is written
+func (l Calibrated) FadeColorContext(ctx context.Context, c Color, t Timing) (int, []byte, error) {
+	c = l.Cal.Apply(c)
+	return l.FadeRGBContext(ctx, t.fade(c.R), t.fade(c.G), t.fade(c.B))
+}
+
+// Fade timing shared by every channel of a colour fade
+type Timing struct {
+	Interval int
+	Step     int
+}
+
+// Returns the fade to the level
+func (t Timing) fade(level byte) Fade {
+	return Fade{
+		Level:    int(level),
+		Interval: t.Interval,
+		Step:     t.Step,
+	}
+}
+
+// CSS Color Module Level 4 named colours
+var cssColors = map[string]Color{
+	"aliceblue":            {240, 248, 255},
+	"antiquewhite":         {250, 235, 215},
+	"aqua":                 {0, 255, 255},
+	"aquamarine":           {127, 255, 212},
+	"azure":                {240, 255, 255},
+	"beige":                {245, 245, 220},
+	"bisque":               {255, 228, 196},
+	"black":                {0, 0, 0},
+	"blanchedalmond":       {255, 235, 205},
+	"blue":                 {0, 0, 255},
+	"blueviolet":           {138, 43, 226},
+	"brown":                {165, 42, 42},
+	"burlywood":            {222, 184, 135},
+	"cadetblue":            {95, 158, 160},
+	"chartreuse":           {127, 255, 0},
+	"chocolate":            {210, 105, 30},
+	"coral":                {255, 127, 80},
+	"cornflowerblue":       {100, 149, 237},
+	"cornsilk":             {255, 248, 220},
+	"crimson":              {220, 20, 60},
+	"cyan":                 {0, 255, 255},
+	"darkblue":             {0, 0, 139},
+	"darkcyan":             {0, 139, 139},
+	"darkgoldenrod":        {184, 134, 11},
+	"darkgray":             {169, 169, 169},
+	"darkgreen":            {0, 100, 0},
+	"darkgrey":             {169, 169, 169},
+	"darkkhaki":            {189, 183, 107},
+	"darkmagenta":          {139, 0, 139},
+	"darkolivegreen":       {85, 107, 47},
+	"darkorange":           {255, 140, 0},
+	"darkorchid":           {153, 50, 204},
+	"darkred":              {139, 0, 0},
+	"darksalmon":           {233, 150, 122},
+	"darkseagreen":         {143, 188, 143},
+	"darkslateblue":        {72, 61, 139},
+	"darkslategray":        {47, 79, 79},
+	"darkslategrey":        {47, 79, 79},
+	"darkturquoise":        {0, 206, 209},
+	"darkviolet":           {148, 0, 211},
+	"deeppink":             {255, 20, 147},
+	"deepskyblue":          {0, 191, 255},
+	"dimgray":              {105, 105, 105},
+	"dimgrey":              {105, 105, 105},
+	"dodgerblue":           {30, 144, 255},
+	"firebrick":            {178, 34, 34},
+	"floralwhite":          {255, 250, 240},
+	"forestgreen":          {34, 139, 34},
+	"fuchsia":              {255, 0, 255},
+	"gainsboro":            {220, 220, 220},
+	"ghostwhite":           {248, 248, 255},
+	"gold":                 {255, 215, 0},
+	"goldenrod":            {218, 165, 32},
+	"gray":                 {128, 128, 128},
+	"green":                {0, 128, 0},
+	"greenyellow":          {173, 255, 47},
+	"grey":                 {128, 128, 128},
+	"honeydew":             {240, 255, 240},
+	"hotpink":              {255, 105, 180},
+	"indianred":            {205, 92, 92},
+	"indigo":               {75, 0, 130},
+	"ivory":                {255, 255, 240},
+	"khaki":                {240, 230, 140},
+	"lavender":             {230, 230, 250},
+	"lavenderblush":        {255, 240, 245},
+	"lawngreen":            {124, 252, 0},
+	"lemonchiffon":         {255, 250, 205},
+	"lightblue":            {173, 216, 230},
+	"lightcoral":           {240, 128, 128},
+	"lightcyan":            {224, 255, 255},
+	"lightgoldenrodyellow": {250, 250, 210},
+	"lightgray":            {211, 211, 211},
+	"lightgreen":           {144, 238, 144},
+	"lightgrey":            {211, 211, 211},
+	"lightpink":            {255, 182, 193},
+	"lightsalmon":          {255, 160, 122},
+	"lightseagreen":        {32, 178, 170},
+	"lightskyblue":         {135, 206, 250},
+	"lightslategray":       {119, 136, 153},
+	"lightslategrey":       {119, 136, 153},
+	"lightsteelblue":       {176, 196, 222},
+	"lightyellow":          {255, 255, 224},
+	"lime":                 {0, 255, 0},
+	"limegreen":            {50, 205, 50},
+	"linen":                {250, 240, 230},
+	"magenta":              {255, 0, 255},
+	"maroon":               {128, 0, 0},
+	"mediumaquamarine":     {102, 205, 170},
+	"mediumblue":           {0, 0, 205},
+	"mediumorchid":         {186, 85, 211},
+	"mediumpurple":         {147, 112, 219},
+	"mediumseagreen":       {60, 179, 113},
+	"mediumslateblue":      {123, 104, 238},
+	"mediumspringgreen":    {0, 250, 154},
+	"mediumturquoise":      {72, 209, 204},
+	"mediumvioletred":      {199, 21, 133},
+	"midnightblue":         {25, 25, 112},
+	"mintcream":            {245, 255, 250},
+	"mistyrose":            {255, 228, 225},
+	"moccasin":             {255, 228, 181},
+	"navajowhite":          {255, 222, 173},
+	"navy":                 {0, 0, 128},
+	"oldlace":              {253, 245, 230},
+	"olive":                {128, 128, 0},
+	"olivedrab":            {107, 142, 35},
+	"orange":               {255, 165, 0},
+	"orangered":            {255, 69, 0},
+	"orchid":               {218, 112, 214},
+	"palegoldenrod":        {238, 232, 170},
+	"palegreen":            {152, 251, 152},
+	"paleturquoise":        {175, 238, 238},
+	"palevioletred":        {219, 112, 147},
+	"papayawhip":           {255, 239, 213},
+	"peachpuff":            {255, 218, 185},
+	"peru":                 {205, 133, 63},
+	"pink":                 {255, 192, 203},
+	"plum":                 {221, 160, 221},
+	"powderblue":           {176, 224, 230},
+	"purple":               {128, 0, 128},
+	"rebeccapurple":        {102, 51, 153},
+	"red":                  {255, 0, 0},
+	"rosybrown":            {188, 143, 143},
+	"royalblue":            {65, 105, 225},
+	"saddlebrown":          {139, 69, 19},
+	"salmon":               {250, 128, 114},
+	"sandybrown":           {244, 164, 96},
+	"seagreen":             {46, 139, 87},
+	"seashell":             {255, 245, 238},
+	"sienna":               {160, 82, 45},
+	"silver":               {192, 192, 192},
+	"skyblue":              {135, 206, 235},
+	"slateblue":            {106, 90, 205},
+	"slategray":            {112, 128, 144},
+	"slategrey":            {112, 128, 144},
+	"snow":                 {255, 250, 250},
+	"springgreen":          {0, 255, 127},
+	"steelblue":            {70, 130, 180},
+	"tan":                  {210, 180, 140},
+	"teal":                 {0, 128, 128},
+	"thistle":              {216, 191, 216},
+	"tomato":               {255, 99, 71},
+	"turquoise":            {64, 224, 208},
+	"violet":               {238, 130, 238},
+	"wheat":                {245, 222, 179},
+	"white":                {255, 255, 255},
+	"whitesmoke":           {245, 245, 245},
+	"yellow":               {255, 255, 0},
+	"yellowgreen":          {154, 205, 50},
+}
@@ -0,0 +1,140 @@
+package lightswarm
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHSV(t *testing.T) {
+	tt := []struct {
+		name     string
+		h, s, v  float64
+		expected Color
+	}{
+		{"red", 0, 1, 1, Color{255, 0, 0}},
+		{"green", 120, 1, 1, Color{0, 255, 0}},
+		{"blue", 240, 1, 1, Color{0, 0, 255}},
+		{"wrapped hue", 420, 1, 1, Color{255, 255, 0}},
+		{"negative hue", -60, 1, 1, Color{255, 0, 255}},
+		{"desaturated", 0, 0.5, 1, Color{255, 128, 128}},
+		{"half value", 180, 1, 0.5, Color{0, 128, 128}},
+		{"white", 0, 0, 1, Color{255, 255, 255}},
+		{"clamped", 0, 2, -1, Color{0, 0, 0}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, HSV(tc.h, tc.s, tc.v))
+		})
+	}
+}
+
+func TestHSL(t *testing.T) {
+	tt := []struct {
+		name     string
+		h, s, l  float64
+		expected Color
+	}{
+		{"red", 0, 1, 0.5, Color{255, 0, 0}},
+		{"light blue", 240, 1, 0.75, Color{128, 128, 255}},
+		{"dark green", 120, 1, 0.25, Color{0, 128, 0}},
+		{"white", 0, 1, 1, Color{255, 255, 255}},
+		{"black", 0, 1, 0, Color{0, 0, 0}},
+		{"grey", 90, 0, 0.5, Color{128, 128, 128}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, HSL(tc.h, tc.s, tc.l))
+		})
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tt := []struct {
+		name     string
+		s        string
+		expected Color
+		err      error
+	}{
+		{"hex", "#55c7ed", Color{85, 199, 237}, nil},
+		{"hex upper case", "#55C7ED", Color{85, 199, 237}, nil},
+		{"hex without hash", "55c7ed", Color{85, 199, 237}, nil},
+		{"short hex", "#f80", Color{255, 136, 0}, nil},
+		{"name", "rebeccapurple", Color{102, 51, 153}, nil},
+		{"name mixed case", " CornflowerBlue ", Color{100, 149, 237}, nil},
+		{"bad hex", "#zzzzzz", Color{}, ErrInvalidColor},
+		{"bad length", "#1234", Color{}, ErrInvalidColor},
+		{"unknown name", "octarine", Color{}, ErrInvalidColor},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := ParseColor(tc.s)
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.expected, c)
+		})
+	}
+}
+
+func TestKelvin(t *testing.T) {
+	tt := []struct {
+		name     string
+		k        float64
+		expected Color
+	}{
+		{"candle", 1900, Color{255, 132, 0}},
+		{"incandescent", 2700, Color{255, 167, 87}},
+		{"daylight", 6500, Color{255, 254, 250}},
+		{"overcast", 10000, Color{202, 218, 255}},
+		{"clamped low", 100, Color{255, 68, 0}},
+		{"clamped high", 100000, Color{152, 186, 255}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Kelvin(tc.k))
+		})
+	}
+}
+
+func TestColorText(t *testing.T) {
+	var v struct {
+		Color Color `json:"color"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(`{"color": "orange"}`), &v))
+	assert.Equal(t, Color{255, 165, 0}, v.Color)
+	b, err := json.Marshal(v)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"color":"#ffa500"}`, string(b))
+	assert.Equal(t, ErrInvalidColor, json.Unmarshal([]byte(`{"color": "nope"}`), &v))
+}
+
+func TestCalibration(t *testing.T) {
+	var none *Calibration
+	assert.Equal(t, Color{1, 2, 3}, none.Apply(Color{1, 2, 3}))
+	linear := NewCalibration([3]float64{}, Color{255, 255, 255})
+	for v := 0; v < 256; v++ {
+		c := Color{byte(v), byte(v), byte(v)}
+		assert.Equal(t, c, linear.Apply(c))
+	}
+	cal := NewCalibration([3]float64{2.2, 1, 1}, Color{255, 200, 100})
+	assert.Equal(t, Color{0, 0, 0}, cal.Apply(Color{0, 0, 0}))
+	assert.Equal(t, Color{255, 200, 100}, cal.Apply(Color{255, 255, 255}))
+	assert.Equal(t, Color{56, 100, 50}, cal.Apply(Color{128, 128, 128}))
+}
+
+func TestCalibrated(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	cal := NewCalibration([3]float64{}, Color{255, 200, 100})
+	led := Calibrated{New(690, buff), cal}
+	_, b, err := led.SetColor(Color{255, 255, 255})
+	assert.Nil(t, err)
+	assert.Equal(t, Frame{Addr: 690, Cmd: SET_RGB_LEVELS, CmdArgs: []byte{255, 200, 100}}.Bytes(), b)
+	_, b, err = led.FadeColor(Color{255, 255, 255}, Timing{Interval: 1, Step: 5})
+	assert.Nil(t, err)
+	expected := Frame{Addr: 690, Cmd: FADE_RGB_TO_LEVEL, CmdArgs: []byte{255, 1, 5, 200, 1, 5, 100, 1, 5}}
+	assert.Equal(t, expected.Bytes(), b)
+	_, b, err = Calibrated{NewGroup(4096, buff), nil}.SetColor(Color{1, 2, 3})
+	assert.Nil(t, err)
+	assert.Equal(t, Frame{Addr: 4096, Cmd: SET_RGB_LEVELS, CmdArgs: []byte{1, 2, 3}}.Bytes(), b)
+}
@@ -0,0 +1,432 @@
+package lightswarm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Command errors
+var (
+	ErrUnknownCommand = errors.New("lightswarm: unknown command")
+	ErrArgs           = errors.New("lightswarm: wrong number of command arguments")
+	ErrArgRange       = errors.New("lightswarm: command argument out of range")
+)
+
+// A typed command, encoding to and decoding from the command byte and
+// arguments of a Frame. Decoding needs a pointer, so the concrete types
+// below implement Command as pointers.
+type Command interface {
+	Opcode() byte
+	MarshalArgs() ([]byte, error)
+	UnmarshalArgs(args []byte) error
+}
+
+// Ensure every command in the constant block has a type
+var (
+	_ Command = (*On)(nil)
+	_ Command = (*Off)(nil)
+	_ Command = (*SetLevel)(nil)
+	_ Command = (*FadeToLevel)(nil)
+	_ Command = (*FadeDown)(nil)
+	_ Command = (*SetPseudoAddress)(nil)
+	_ Command = (*ErasePseudoAddressTable)(nil)
+	_ Command = (*SetRGBLevels)(nil)
+	_ Command = (*Toggle)(nil)
+	_ Command = (*FadeMultipleToLevel)(nil)
+	_ Command = (*FadeRGBToLevel)(nil)
+)
+
+// Constructors for decoding each opcode
+var (
+	commandsMu sync.RWMutex
+	commands   = map[byte]func() Command{
+		ON:                         func() Command { return &On{} },
+		OFF:                        func() Command { return &Off{} },
+		SET_LEVEL:                  func() Command { return &SetLevel{} },
+		FADE_TO_LEVEL:              func() Command { return &FadeToLevel{} },
+		FADE_DOWN:                  func() Command { return &FadeDown{} },
+		SET_PSUEDO_ADDRESS:         func() Command { return &SetPseudoAddress{} },
+		ERASE_PSUEDO_ADDRESS_TABLE: func() Command { return &ErasePseudoAddressTable{} },
+		SET_RGB_LEVELS:             func() Command { return &SetRGBLevels{} },
+		TOGGLE:                     func() Command { return &Toggle{} },
+		FADE_MULTIPLE_TO_LEVEL:     func() Command { return &FadeMultipleToLevel{} },
+		FADE_RGB_TO_LEVEL:          func() Command { return &FadeRGBToLevel{} },
+	}
+)
+
+// Registers the constructor used to decode frames with the opcode, for
+// commands added by custom firmware. Registering a built in opcode
+// replaces it.
+func RegisterCommand(opcode byte, fn func() Command) {
+	commandsMu.Lock()
+	defer commandsMu.Unlock()
+	commands[opcode] = fn
+}
+
+// Returns a new zero command for the opcode, ErrUnknownCommand is returned
+// if the opcode is not registered
+func NewCommand(opcode byte) (Command, error) {
+	commandsMu.RLock()
+	fn, ok := commands[opcode]
+	commandsMu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownCommand
+	}
+	return fn(), nil
+}
+
+// Builds the frame sending the command to the address
+func NewFrame(addr uint16, cmd Command) (Frame, error) {
+	args, err := cmd.MarshalArgs()
+	if err != nil {
+		return Frame{}, err
+	}
+	return Frame{Addr: addr, Cmd: cmd.Opcode(), CmdArgs: args}, nil
+}
+
+// Decodes the frame command byte and arguments into a typed command
+func (f Frame) Command() (Command, error) {
+	cmd, err := NewCommand(f.Cmd)
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.UnmarshalArgs(f.CmdArgs); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// Checks the fade can be sent, zero intervals and steps are sent as 1
+func (f Fade) validate() error {
+	if f.Level < 0 || f.Level > 255 ||
+		f.Interval < 0 || f.Interval > 255 ||
+		f.Step < 0 || f.Step > 127 {
+		return ErrArgRange
+	}
+	return nil
+}
+
+// Decodes three bytes of Fade.Args
+func unmarshalFade(args []byte) (Fade, error) {
+	f := Fade{
+		Level:    int(args[0]),
+		Interval: int(args[1]),
+		Step:     int(args[2]),
+	}
+	if f.Interval < 1 || f.Step < 1 || f.Step > 127 {
+		return Fade{}, ErrArgRange
+	}
+	return f, nil
+}
+
+// Checks the arguments have the expected length
+func checkArgs(args []byte, n int) error {
+	if len(args) != n {
+		return ErrArgs
+	}
+	return nil
+}
+
+// Turns the LED on
+type On struct{}
+
+func (On) Opcode() byte {
+	return ON
+}
+
+func (On) MarshalArgs() ([]byte, error) {
+	return nil, nil
+}
+
+func (*On) UnmarshalArgs(args []byte) error {
+	return checkArgs(args, 0)
+}
+
+func (On) String() string {
+	return "ON"
+}
+
+// Turns the LED off
+type Off struct{}
+
+func (Off) Opcode() byte {
+	return OFF
+}
+
+func (Off) MarshalArgs() ([]byte, error) {
+	return nil, nil
+}
+
+func (*Off) UnmarshalArgs(args []byte) error {
+	return checkArgs(args, 0)
+}
+
+func (Off) String() string {
+	return "OFF"
+}
+
+// Toggles the LED on or off
+type Toggle struct{}
+
+func (Toggle) Opcode() byte {
+	return TOGGLE
+}
+
+func (Toggle) MarshalArgs() ([]byte, error) {
+	return nil, nil
+}
+
+func (*Toggle) UnmarshalArgs(args []byte) error {
+	return checkArgs(args, 0)
+}
+
+func (Toggle) String() string {
+	return "TOGGLE"
+}
+
+// Sets the light level immediately
+type SetLevel struct {
+	Level byte
+}
+
+func (SetLevel) Opcode() byte {
+	return SET_LEVEL
+}
+
+func (c SetLevel) MarshalArgs() ([]byte, error) {
+	return []byte{c.Level}, nil
+}
+
+func (c *SetLevel) UnmarshalArgs(args []byte) error {
+	if err := checkArgs(args, 1); err != nil {
+		return err
+	}
+	c.Level = args[0]
+	return nil
+}
+
+func (c SetLevel) String() string {
+	return fmt.Sprintf("SET_LEVEL level=%d", c.Level)
+}
+
+// Formats a fade as (level,interval,step)
+func fadeString(f Fade) string {
+	return fmt.Sprintf("(%d,%d,%d)", f.Level, f.Interval, f.Step)
+}
+
+// Fades to a light level
+type FadeToLevel struct {
+	Fade Fade
+}
+
+func (FadeToLevel) Opcode() byte {
+	return FADE_TO_LEVEL
+}
+
+func (c FadeToLevel) MarshalArgs() ([]byte, error) {
+	if err := c.Fade.validate(); err != nil {
+		return nil, err
+	}
+	return c.Fade.Args(), nil
+}
+
+func (c *FadeToLevel) UnmarshalArgs(args []byte) error {
+	if err := checkArgs(args, 3); err != nil {
+		return err
+	}
+	f, err := unmarshalFade(args)
+	if err != nil {
+		return err
+	}
+	c.Fade = f
+	return nil
+}
+
+func (c FadeToLevel) String() string {
+	return "FADE_TO_LEVEL fade=" + fadeString(c.Fade)
+}
+
+// Legacy fade down, only fades if the level is below the current level
+type FadeDown struct {
+	Fade Fade
+}
+
+func (FadeDown) Opcode() byte {
+	return FADE_DOWN
+}
+
+func (c FadeDown) MarshalArgs() ([]byte, error) {
+	return FadeToLevel(c).MarshalArgs()
+}
+
+func (c *FadeDown) UnmarshalArgs(args []byte) error {
+	return (*FadeToLevel)(c).UnmarshalArgs(args)
+}
+
+func (c FadeDown) String() string {
+	return "FADE_DOWN fade=" + fadeString(c.Fade)
+}
+
+// Adds a pseudo address to the LED's pseudo address table
+type SetPseudoAddress struct {
+	Addr uint16
+}
+
+func (SetPseudoAddress) Opcode() byte {
+	return SET_PSUEDO_ADDRESS
+}
+
+func (c SetPseudoAddress) MarshalArgs() ([]byte, error) {
+	return addrBytes(c.Addr), nil
+}
+
+func (c *SetPseudoAddress) UnmarshalArgs(args []byte) error {
+	if err := checkArgs(args, 2); err != nil {
+		return err
+	}
+	c.Addr = binary.BigEndian.Uint16(args)
+	return nil
+}
+
+func (c SetPseudoAddress) String() string {
+	return fmt.Sprintf("SET_PSUEDO_ADDRESS psuedo=%d", c.Addr)
+}
+
+// Erases all pseudo addresses from the LED's pseudo address table
+type ErasePseudoAddressTable struct{}
+
+func (ErasePseudoAddressTable) Opcode() byte {
+	return ERASE_PSUEDO_ADDRESS_TABLE
+}
+
+func (ErasePseudoAddressTable) MarshalArgs() ([]byte, error) {
+	return nil, nil
+}
+
+func (*ErasePseudoAddressTable) UnmarshalArgs(args []byte) error {
+	return checkArgs(args, 0)
+}
+
+func (ErasePseudoAddressTable) String() string {
+	return "ERASE_PSUEDO_ADDRESS_TABLE"
+}
+
+// Sets the Red, Green and Blue levels immediately
+type SetRGBLevels struct {
+	R, G, B byte
+}
+
+func (SetRGBLevels) Opcode() byte {
+	return SET_RGB_LEVELS
+}
+
+func (c SetRGBLevels) MarshalArgs() ([]byte, error) {
+	return []byte{c.R, c.G, c.B}, nil
+}
+
+func (c *SetRGBLevels) UnmarshalArgs(args []byte) error {
+	if err := checkArgs(args, 3); err != nil {
+		return err
+	}
+	c.R, c.G, c.B = args[0], args[1], args[2]
+	return nil
+}
+
+func (c SetRGBLevels) String() string {
+	return fmt.Sprintf("SET_RGB_LEVELS r=%d g=%d b=%d", c.R, c.G, c.B)
+}
+
+// Fades the Red, Green and Blue levels
+type FadeRGBToLevel struct {
+	R, G, B Fade
+}
+
+func (FadeRGBToLevel) Opcode() byte {
+	return FADE_RGB_TO_LEVEL
+}
+
+func (c FadeRGBToLevel) MarshalArgs() ([]byte, error) {
+	args := []byte{}
+	for _, f := range []Fade{c.R, c.G, c.B} {
+		if err := f.validate(); err != nil {
+			return nil, err
+		}
+		args = append(args, f.Args()...)
+	}
+	return args, nil
+}
+
+func (c *FadeRGBToLevel) UnmarshalArgs(args []byte) error {
+	if err := checkArgs(args, 9); err != nil {
+		return err
+	}
+	fades := [3]Fade{}
+	for i := range fades {
+		f, err := unmarshalFade(args[i*3 : i*3+3])
+		if err != nil {
+			return err
+		}
+		fades[i] = f
+	}
+	c.R, c.G, c.B = fades[0], fades[1], fades[2]
+	return nil
+}
+
+func (c FadeRGBToLevel) String() string {
+	return fmt.Sprintf("FADE_RGB_TO_LEVEL r=%s g=%s b=%s", fadeString(c.R), fadeString(c.G), fadeString(c.B))
+}
+
+// Fades many addresses at once, sent to an address every node receives. Between 1
+// and MaxMultipleFades fades fit in a frame, FadeMultipleFrames splits
+// larger batches.
+type FadeMultipleToLevel struct {
+	Fades []AddressFade
+}
+
+func (FadeMultipleToLevel) Opcode() byte {
+	return FADE_MULTIPLE_TO_LEVEL
+}
+
+func (c FadeMultipleToLevel) MarshalArgs() ([]byte, error) {
+	if len(c.Fades) == 0 || len(c.Fades) > MaxMultipleFades {
+		return nil, ErrArgs
+	}
+	args := []byte{}
+	for _, af := range c.Fades {
+		if err := af.Fade.validate(); err != nil {
+			return nil, err
+		}
+		args = append(args, af.Args()...)
+	}
+	return args, nil
+}
+
+func (c *FadeMultipleToLevel) UnmarshalArgs(args []byte) error {
+	if len(args) == 0 || len(args)%multipleFadeLen != 0 || len(args)/multipleFadeLen > MaxMultipleFades {
+		return ErrArgs
+	}
+	fades := []AddressFade{}
+	for i := 0; i < len(args); i += multipleFadeLen {
+		f, err := unmarshalFade(args[i+2 : i+multipleFadeLen])
+		if err != nil {
+			return err
+		}
+		fades = append(fades, AddressFade{
+			Addr: binary.BigEndian.Uint16(args[i : i+2]),
+			Fade: f,
+		})
+	}
+	c.Fades = fades
+	return nil
+}
+
+func (c FadeMultipleToLevel) String() string {
+	fades := []string{"FADE_MULTIPLE_TO_LEVEL"}
+	for _, af := range c.Fades {
+		fades = append(fades, fmt.Sprintf("%d=%s", af.Addr, fadeString(af.Fade)))
+	}
+	return strings.Join(fades, " ")
+}
@@ -0,0 +1,225 @@
+package lightswarm
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandRoundTrip(t *testing.T) {
+	tt := []struct {
+		name     string
+		cmd      Command
+		expected Frame
+	}{
+		{
+			"on",
+			&On{},
+			Frame{Addr: 690, Cmd: ON},
+		},
+		{
+			"off",
+			&Off{},
+			Frame{Addr: 690, Cmd: OFF},
+		},
+		{
+			"toggle",
+			&Toggle{},
+			Frame{Addr: 690, Cmd: TOGGLE},
+		},
+		{
+			"set level",
+			&SetLevel{Level: 128},
+			Frame{Addr: 690, Cmd: SET_LEVEL, CmdArgs: []byte{128}},
+		},
+		{
+			"fade to level",
+			&FadeToLevel{Fade: Fade{Level: 255, Interval: 1, Step: 5}},
+			Frame{Addr: 690, Cmd: FADE_TO_LEVEL, CmdArgs: []byte{255, 1, 5}},
+		},
+		{
+			"fade down",
+			&FadeDown{Fade: Fade{Level: 0, Interval: 2, Step: 3}},
+			Frame{Addr: 690, Cmd: FADE_DOWN, CmdArgs: []byte{0, 2, 3}},
+		},
+		{
+			"set pseudo address",
+			&SetPseudoAddress{Addr: 4096},
+			Frame{Addr: 690, Cmd: SET_PSUEDO_ADDRESS, CmdArgs: []byte{16, 0}},
+		},
+		{
+			"erase pseudo address table",
+			&ErasePseudoAddressTable{},
+			Frame{Addr: 690, Cmd: ERASE_PSUEDO_ADDRESS_TABLE},
+		},
+		{
+			"set rgb levels",
+			&SetRGBLevels{R: 85, G: 199, B: 237},
+			Frame{Addr: 690, Cmd: SET_RGB_LEVELS, CmdArgs: []byte{85, 199, 237}},
+		},
+		{
+			"fade rgb to level",
+			&FadeRGBToLevel{
+				R: Fade{Level: 85, Interval: 1, Step: 1},
+				G: Fade{Level: 199, Interval: 2, Step: 3},
+				B: Fade{Level: 237, Interval: 4, Step: 127},
+			},
+			Frame{Addr: 690, Cmd: FADE_RGB_TO_LEVEL, CmdArgs: []byte{85, 1, 1, 199, 2, 3, 237, 4, 127}},
+		},
+		{
+			"fade multiple to level",
+			&FadeMultipleToLevel{Fades: []AddressFade{
+				{Addr: 690, Fade: Fade{Level: 255, Interval: 1, Step: 5}},
+				{Addr: 227, Fade: Fade{Level: 0, Interval: 2, Step: 1}},
+			}},
+			Frame{Addr: 690, Cmd: FADE_MULTIPLE_TO_LEVEL, CmdArgs: []byte{2, 178, 255, 1, 5, 0, 227, 0, 2, 1}},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			frame, err := NewFrame(690, tc.cmd)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, frame)
+			decoded, err := ParseFrame(frame.Bytes())
+			assert.NoError(t, err)
+			cmd, err := decoded.Command()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.cmd, cmd)
+		})
+	}
+}
+
+func TestCommandMatchesLED(t *testing.T) {
+	led := func(fn func(*LED) (int, []byte, error)) []byte {
+		_, b, _ := fn(New(690, &bytes.Buffer{}))
+		return b
+	}
+	send := func(cmd Command) []byte {
+		_, b, err := New(690, &bytes.Buffer{}).Send(cmd)
+		assert.NoError(t, err)
+		return b
+	}
+	fade := Fade{Level: 255, Interval: 1, Step: 5}
+	assert.Equal(t, led((*LED).On), send(&On{}))
+	assert.Equal(t, led((*LED).ErasePseudoAddressTable), send(&ErasePseudoAddressTable{}))
+	assert.Equal(t, led(func(l *LED) (int, []byte, error) { return l.Fade(fade) }), send(&FadeToLevel{Fade: fade}))
+	assert.Equal(t, led(func(l *LED) (int, []byte, error) { return l.SetPseudoAddress(4096) }), send(&SetPseudoAddress{Addr: 4096}))
+	assert.Equal(t, led(func(l *LED) (int, []byte, error) { return l.FadeRGB(fade, fade, fade) }), send(&FadeRGBToLevel{fade, fade, fade}))
+	_, multiple, _ := FadeMultiple(&bytes.Buffer{}, 4095, AddressFade{Addr: 690, Fade: fade})
+	_, sent, err := New(4095, &bytes.Buffer{}).Send(&FadeMultipleToLevel{Fades: []AddressFade{{Addr: 690, Fade: fade}}})
+	assert.NoError(t, err)
+	assert.Equal(t, multiple, sent)
+}
+
+func TestLEDRejectsOutOfRangeFade(t *testing.T) {
+	buff := &bytes.Buffer{}
+	led := New(690, buff)
+	n, b, err := led.Fade(Fade{Level: 300})
+	assert.Equal(t, ErrArgRange, err)
+	assert.Equal(t, 0, n)
+	assert.Nil(t, b)
+	_, _, err = NewGroup(4096, buff).FadeRGB(Fade{}, Fade{Step: 128}, Fade{})
+	assert.Equal(t, ErrArgRange, err)
+	assert.Equal(t, 0, buff.Len())
+}
+
+func TestCommandMarshalArgsErrors(t *testing.T) {
+	many := make([]AddressFade, MaxMultipleFades+1)
+	tt := []struct {
+		name string
+		cmd  Command
+		err  error
+	}{
+		{"fade level too high", &FadeToLevel{Fade: Fade{Level: 256}}, ErrArgRange},
+		{"fade level negative", &FadeDown{Fade: Fade{Level: -1}}, ErrArgRange},
+		{"fade interval too long", &FadeToLevel{Fade: Fade{Interval: 256}}, ErrArgRange},
+		{"fade step too big", &FadeToLevel{Fade: Fade{Step: 128}}, ErrArgRange},
+		{"fade rgb channel out of range", &FadeRGBToLevel{G: Fade{Level: 300}}, ErrArgRange},
+		{"fade multiple empty", &FadeMultipleToLevel{}, ErrArgs},
+		{"fade multiple too many", &FadeMultipleToLevel{Fades: many}, ErrArgs},
+		{"fade multiple out of range", &FadeMultipleToLevel{Fades: []AddressFade{{Fade: Fade{Step: 200}}}}, ErrArgRange},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewFrame(690, tc.cmd)
+			assert.Equal(t, tc.err, err)
+		})
+	}
+}
+
+func TestFrameCommandErrors(t *testing.T) {
+	tt := []struct {
+		name  string
+		frame Frame
+		err   error
+	}{
+		{"unknown command", Frame{Cmd: 0x2F}, ErrUnknownCommand},
+		{"on with arguments", Frame{Cmd: ON, CmdArgs: []byte{1}}, ErrArgs},
+		{"set level without arguments", Frame{Cmd: SET_LEVEL}, ErrArgs},
+		{"set rgb missing blue", Frame{Cmd: SET_RGB_LEVELS, CmdArgs: []byte{1, 2}}, ErrArgs},
+		{"psuedo address too short", Frame{Cmd: SET_PSUEDO_ADDRESS, CmdArgs: []byte{1}}, ErrArgs},
+		{"fade zero interval", Frame{Cmd: FADE_TO_LEVEL, CmdArgs: []byte{255, 0, 1}}, ErrArgRange},
+		{"fade step too big", Frame{Cmd: FADE_DOWN, CmdArgs: []byte{255, 1, 128}}, ErrArgRange},
+		{"fade rgb short", Frame{Cmd: FADE_RGB_TO_LEVEL, CmdArgs: make([]byte, 8)}, ErrArgs},
+		{"fade rgb zero step", Frame{Cmd: FADE_RGB_TO_LEVEL, CmdArgs: []byte{1, 1, 1, 1, 1, 0, 1, 1, 1}}, ErrArgRange},
+		{"fade multiple partial", Frame{Cmd: FADE_MULTIPLE_TO_LEVEL, CmdArgs: make([]byte, 7)}, ErrArgs},
+		{"fade multiple empty", Frame{Cmd: FADE_MULTIPLE_TO_LEVEL}, ErrArgs},
+		{"fade multiple zero step", Frame{Cmd: FADE_MULTIPLE_TO_LEVEL, CmdArgs: []byte{2, 178, 255, 1, 0}}, ErrArgRange},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd, err := tc.frame.Command()
+			assert.Nil(t, cmd)
+			assert.Equal(t, tc.err, err)
+		})
+	}
+}
+
+// A command added by custom firmware
+type blink struct {
+	Times byte
+}
+
+func (blink) Opcode() byte {
+	return 0x40
+}
+
+func (c blink) MarshalArgs() ([]byte, error) {
+	return []byte{c.Times}, nil
+}
+
+func (c *blink) UnmarshalArgs(args []byte) error {
+	if err := checkArgs(args, 1); err != nil {
+		return err
+	}
+	c.Times = args[0]
+	return nil
+}
+
+func TestRegisterCommand(t *testing.T) {
+	defer func() {
+		commandsMu.Lock()
+		delete(commands, 0x40)
+		commandsMu.Unlock()
+	}()
+	_, err := NewCommand(0x40)
+	assert.Equal(t, ErrUnknownCommand, err)
+	RegisterCommand(0x40, func() Command { return &blink{} })
+	frame, err := NewFrame(690, &blink{Times: 3})
+	assert.NoError(t, err)
+	cmd, err := frame.Command()
+	assert.NoError(t, err)
+	assert.Equal(t, &blink{Times: 3}, cmd)
+	assert.Equal(t, "690 0x40 args=03", frame.String())
+}
+
+func TestLEDSendContext(t *testing.T) {
+	buff := &bytes.Buffer{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := New(690, buff).SendContext(ctx, &On{})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 0, buff.Len())
+}
@@ -0,0 +1,39 @@
+package lightswarm
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Implemented by writers which can abandon a write when the context is
+// done, such as Bus and Pacer which queue writes. Frames waiting in the
+// queue when the context is done are dropped rather than written late.
+type ContextWriter interface {
+	WriteContext(ctx context.Context, p []byte) (int, error)
+}
+
+// Writes to the given writer, using WriteContext if it is implemented.
+// Plain writers cannot be interrupted so the context is only checked
+// before the write starts.
+func WriteContext(ctx context.Context, w io.Writer, p []byte) (int, error) {
+	if cw, ok := w.(ContextWriter); ok {
+		return cw.WriteContext(ctx, p)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.Write(p)
+}
+
+// Sleeps for the given duration or until the context is done
+func SleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
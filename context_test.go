@@ -0,0 +1,175 @@
+package lightswarm
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A writer that blocks until released
+type blockingWriter struct {
+	started chan struct{}
+	release chan struct{}
+	buf     bytes.Buffer
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.started <- struct{}{}
+	<-w.release
+	return w.buf.Write(p)
+}
+
+// Returns a context which has already been cancelled
+func cancelled() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+func TestWriteContext(t *testing.T) {
+	tt := []struct {
+		name     string
+		ctx      context.Context
+		expected []byte
+		n        int
+		err      error
+	}{
+		{
+			"background context",
+			context.Background(),
+			[]byte{END},
+			1,
+			nil,
+		},
+		{
+			"cancelled context",
+			cancelled(),
+			nil,
+			0,
+			context.Canceled,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			buff := bytes.NewBuffer(nil)
+			n, err := WriteContext(tc.ctx, buff, []byte{END})
+			assert.Equal(t, tc.n, n)
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.expected, buff.Bytes())
+		})
+	}
+}
+
+func TestSleepContext(t *testing.T) {
+	assert.Nil(t, SleepContext(context.Background(), time.Millisecond))
+	assert.Equal(t, context.Canceled, SleepContext(cancelled(), time.Hour))
+}
+
+func TestLEDContext(t *testing.T) {
+	tt := []struct {
+		name string
+		cmd  func(*LED, context.Context) (int, []byte, error)
+	}{
+		{"on", (*LED).OnContext},
+		{"off", (*LED).OffContext},
+		{"toggle", (*LED).ToggleContext},
+		{"set level", func(led *LED, ctx context.Context) (int, []byte, error) {
+			return led.SetLevelContext(ctx, 1)
+		}},
+		{"fade down", func(led *LED, ctx context.Context) (int, []byte, error) {
+			return led.FadeDownContext(ctx, Fade{0, 1, 1})
+		}},
+		{"fade", func(led *LED, ctx context.Context) (int, []byte, error) {
+			return led.FadeContext(ctx, Fade{255, 1, 1})
+		}},
+		{"set rgb", func(led *LED, ctx context.Context) (int, []byte, error) {
+			return led.SetRGBContext(ctx, 1, 2, 3)
+		}},
+		{"fade rgb", func(led *LED, ctx context.Context) (int, []byte, error) {
+			return led.FadeRGBContext(ctx, Fade{1, 1, 1}, Fade{2, 1, 1}, Fade{3, 1, 1})
+		}},
+		{"set psuedo address", func(led *LED, ctx context.Context) (int, []byte, error) {
+			return led.SetPseudoAddressContext(ctx, 4096)
+		}},
+		{"erase psuedo address table", (*LED).ErasePseudoAddressTableContext},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			buff := bytes.NewBuffer(nil)
+			led := &LED{690, buff}
+			n, b, err := tc.cmd(led, context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, len(b), n)
+			assert.Equal(t, b, buff.Bytes())
+			buff.Reset()
+			n, b, err = tc.cmd(led, cancelled())
+			assert.Equal(t, context.Canceled, err)
+			assert.Equal(t, 0, n)
+			assert.Nil(t, b)
+			assert.Equal(t, 0, buff.Len())
+		})
+	}
+}
+
+func TestGroupContext(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	group := &Group{4096, buff}
+	_, _, err := group.OnContext(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{END, 16, 0, ON, 48, END}, buff.Bytes())
+	_, _, err = group.FadeRGBContext(cancelled(), Fade{}, Fade{}, Fade{})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestFadeMultipleContext(t *testing.T) {
+	_, _, err := FadeMultipleContext(cancelled(), ioutil.Discard, 4095, AddressFade{690, Fade{}})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestBusWriteContextDropsQueuedFrames(t *testing.T) {
+	w := &blockingWriter{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	bus := NewBus(w)
+	defer bus.Close()
+	// wedge the bus with a first write
+	done := make(chan error)
+	go func() {
+		_, _, err := bus.LED(690).On()
+		done <- err
+	}()
+	<-w.started
+	// a second write is queued behind it and times out
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	_, _, err := bus.LED(690).OffContext(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	// release the first write, the timed out frame is never written
+	close(w.release)
+	assert.Nil(t, <-done)
+	assert.Equal(t, []byte{END, 2, 178, ON, 144, END}, w.buf.Bytes())
+}
+
+func TestBusWriteContextInFlight(t *testing.T) {
+	w := &blockingWriter{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	bus := NewBus(w)
+	defer bus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := bus.WriteContext(ctx, []byte{END})
+		done <- err
+	}()
+	<-w.started
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+	close(w.release)
+}
@@ -0,0 +1,101 @@
+package lightswarm
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Frame decoding errors
+var (
+	ErrChecksum       = errors.New("lightswarm: bad frame checksum")
+	ErrTruncated      = errors.New("lightswarm: truncated frame")
+	ErrDanglingEscape = errors.New("lightswarm: dangling escape byte")
+	ErrUnknownEscape  = errors.New("lightswarm: unknown escape sequence")
+	ErrUnexpectedEnd  = errors.New("lightswarm: unexpected end byte inside frame")
+	ErrFrameTooLong   = errors.New("lightswarm: frame too long")
+)
+
+// Minimum number of unescaped bytes in a frame:
+// 2 address bytes, 1 command byte and 1 checksum byte
+const minFrameLen = 4
+
+// Maximum number of unescaped bytes in a frame built by the library:
+// 2 address bytes, 1 command byte, up to 80 argument bytes and 1 checksum
+// byte. The LightSwarm protocol does not document a maximum frame length,
+// so this is a limit of the library rather than of the protocol. It sets
+// how many fades fit in a FADE_MULTIPLE_TO_LEVEL frame, see
+// MaxMultipleFades.
+const MaxFrameLen = 84
+
+// Strips any leading and trailing END bytes from the given bytes
+func trim(bs []byte) []byte {
+	for len(bs) > 0 && bs[0] == END {
+		bs = bs[1:]
+	}
+	for len(bs) > 0 && bs[len(bs)-1] == END {
+		bs = bs[:len(bs)-1]
+	}
+	return bs
+}
+
+// Reverses the escape sequences applied by Frame.wrap, the given
+// bytes must already have their END delimiters removed
+func unwrap(bs []byte) ([]byte, error) {
+	frame := make([]byte, 0, len(bs))
+	for i := 0; i < len(bs); i++ {
+		switch bs[i] {
+		case END:
+			return nil, ErrUnexpectedEnd
+		case ESC:
+			i++
+			if i == len(bs) {
+				return nil, ErrDanglingEscape
+			}
+			switch bs[i] {
+			case ENDSEQ[1]:
+				frame = append(frame, END)
+			case ESCSEQ[1]:
+				frame = append(frame, ESC)
+			default:
+				return nil, ErrUnknownEscape
+			}
+		default:
+			frame = append(frame, bs[i])
+		}
+	}
+	return frame, nil
+}
+
+// Decodes wire bytes, as produced by Frame.Bytes, into the frame.
+// The END delimiters are optional, any number of them are stripped
+// from either side of the data before it is unescaped and the checksum
+// verified.
+func (f *Frame) UnmarshalBinary(data []byte) error {
+	bs, err := unwrap(trim(data))
+	if err != nil {
+		return err
+	}
+	if len(bs) < minFrameLen {
+		return ErrTruncated
+	}
+	body, checksum := bs[:len(bs)-1], bs[len(bs)-1]
+	if f.checksum(body) != checksum {
+		return ErrChecksum
+	}
+	f.Addr = binary.BigEndian.Uint16(body[0:2])
+	f.Cmd = body[2]
+	f.CmdArgs = nil
+	if len(body) > 3 {
+		f.CmdArgs = append([]byte{}, body[3:]...)
+	}
+	return nil
+}
+
+// Parses the given wire bytes into a Frame
+func ParseFrame(data []byte) (Frame, error) {
+	var f Frame
+	if err := f.UnmarshalBinary(data); err != nil {
+		return Frame{}, err
+	}
+	return f, nil
+}
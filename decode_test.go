@@ -0,0 +1,193 @@
+package lightswarm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrim(t *testing.T) {
+	tt := []struct {
+		name     string
+		bs       []byte
+		expected []byte
+	}{
+		{
+			"no end bytes",
+			[]byte{2, 178, ON, 144},
+			[]byte{2, 178, ON, 144},
+		},
+		{
+			"single end bytes",
+			[]byte{END, 2, 178, ON, 144, END},
+			[]byte{2, 178, ON, 144},
+		},
+		{
+			"back to back end bytes",
+			[]byte{END, END, 2, 178, ON, 144, END, END},
+			[]byte{2, 178, ON, 144},
+		},
+		{
+			"only end bytes",
+			[]byte{END, END},
+			[]byte{},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, trim(tc.bs))
+		})
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	tt := []struct {
+		name     string
+		bs       []byte
+		expected []byte
+		err      error
+	}{
+		{
+			"no escapes",
+			[]byte{2, 178, ON, 144},
+			[]byte{2, 178, ON, 144},
+			nil,
+		},
+		{
+			"end byte escape",
+			[]byte{2, 226, ON, ESC, 0xDC},
+			[]byte{2, 226, ON, END},
+			nil,
+		},
+		{
+			"esc byte escape",
+			[]byte{ESC, 0xDD},
+			[]byte{ESC},
+			nil,
+		},
+		{
+			"dangling escape",
+			[]byte{2, 226, ON, ESC},
+			nil,
+			ErrDanglingEscape,
+		},
+		{
+			"unknown escape",
+			[]byte{2, 226, ON, ESC, 0x01},
+			nil,
+			ErrUnknownEscape,
+		},
+		{
+			"end byte inside frame",
+			[]byte{2, 226, END, ON},
+			nil,
+			ErrUnexpectedEnd,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			bs, err := unwrap(tc.bs)
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.expected, bs)
+		})
+	}
+}
+
+func TestParseFrame(t *testing.T) {
+	tt := []struct {
+		name     string
+		bs       []byte
+		expected Frame
+		err      error
+	}{
+		{
+			"turn 690 on",
+			[]byte{END, 2, 178, ON, 144, END},
+			Frame{690, ON, nil},
+			nil,
+		},
+		{
+			"turn 738 on with escaped checksum",
+			[]byte{END, 2, 226, ON, ESC, 0xDC, END},
+			Frame{738, ON, nil},
+			nil,
+		},
+		{
+			"fade 690 to 255 at 1 step per 1 interval",
+			[]byte{END, 2, 178, FADE_TO_LEVEL, 255, 1, 1, 108, END},
+			Frame{690, FADE_TO_LEVEL, []byte{255, 1, 1}},
+			nil,
+		},
+		{
+			"without end bytes",
+			[]byte{2, 178, ON, 144},
+			Frame{690, ON, nil},
+			nil,
+		},
+		{
+			"bad checksum",
+			[]byte{END, 2, 178, ON, 145, END},
+			Frame{},
+			ErrChecksum,
+		},
+		{
+			"truncated",
+			[]byte{END, 2, 178, END},
+			Frame{},
+			ErrTruncated,
+		},
+		{
+			"empty",
+			[]byte{END, END},
+			Frame{},
+			ErrTruncated,
+		},
+		{
+			"dangling escape",
+			[]byte{END, 2, 226, ON, ESC, END},
+			Frame{},
+			ErrDanglingEscape,
+		},
+		{
+			"unknown escape",
+			[]byte{END, 2, 226, ON, ESC, 0x00, END},
+			Frame{},
+			ErrUnknownEscape,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := ParseFrame(tc.bs)
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.expected, f)
+		})
+	}
+}
+
+func TestFrameUnmarshalBinaryRoundTrip(t *testing.T) {
+	tt := []struct {
+		name  string
+		frame Frame
+	}{
+		{
+			"turn 738 on",
+			Frame{738, ON, nil},
+		},
+		{
+			"set 690 RGB to 85, 199, 237",
+			Frame{690, SET_RGB_LEVELS, []byte{85, 199, 237}},
+		},
+		{
+			"args containing end and esc bytes",
+			Frame{0xC0DB, FADE_RGB_TO_LEVEL, []byte{END, ESC, 1, ESC, END, 1, 255, 1, 1}},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var f Frame
+			err := f.UnmarshalBinary(tc.frame.Bytes())
+			assert.Nil(t, err)
+			assert.Equal(t, tc.frame, f)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package lightswarm
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Command constant names
+var commandNames = map[byte]string{
+	ON:                         "ON",
+	OFF:                        "OFF",
+	SET_LEVEL:                  "SET_LEVEL",
+	FADE_TO_LEVEL:              "FADE_TO_LEVEL",
+	FADE_DOWN:                  "FADE_DOWN",
+	SET_PSUEDO_ADDRESS:         "SET_PSUEDO_ADDRESS",
+	ERASE_PSUEDO_ADDRESS_TABLE: "ERASE_PSUEDO_ADDRESS_TABLE",
+	SET_RGB_LEVELS:             "SET_RGB_LEVELS",
+	TOGGLE:                     "TOGGLE",
+	FADE_MULTIPLE_TO_LEVEL:     "FADE_MULTIPLE_TO_LEVEL",
+	FADE_RGB_TO_LEVEL:          "FADE_RGB_TO_LEVEL",
+}
+
+// Returns the name of the command constant, or the command byte in hex
+// if it is unknown
+func CommandName(cmd byte) string {
+	if name, ok := commandNames[cmd]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%02x", cmd)
+}
+
+// Returns the frame as a human readable line, the address followed by
+// the command name and its arguments:
+//
+//	690 FADE_RGB_TO_LEVEL r=(255,1,1) g=(0,1,1) b=(0,1,1)
+//
+// Fade arguments are shown as (level,interval,step). Arguments which do
+// not decode into the command, or belong to an unknown command, are shown
+// as raw hex.
+func (f Frame) String() string {
+	s := fmt.Sprint(f.Addr) + " "
+	cmd, err := f.Command()
+	if stringer, ok := cmd.(fmt.Stringer); ok && err == nil {
+		return s + stringer.String()
+	}
+	s += CommandName(f.Cmd)
+	switch {
+	case len(f.CmdArgs) > 0:
+		s += fmt.Sprintf(" args=% x", f.CmdArgs)
+	case err != nil:
+		s += " args=none"
+	}
+	return s
+}
+
+// Returns a human readable line describing the wire bytes of a single
+// frame, as Frame.String followed by whether the checksum is correct:
+//
+//	690 ON chk=OK
+//	690 ON chk=BAD(got 0x91 want 0x90)
+//
+// Frames with a bad checksum are still decoded, so the line shows what
+// the frame would have done. Bytes which cannot be decoded at all are
+// described with the decoding error.
+func Describe(data []byte) string {
+	bs, err := unwrap(trim(data))
+	if err == nil && len(bs) < minFrameLen {
+		err = ErrTruncated
+	}
+	if err != nil {
+		return fmt.Sprintf("invalid frame % x: %s", data, err)
+	}
+	body, checksum := bs[:len(bs)-1], bs[len(bs)-1]
+	f := Frame{
+		Addr:    binary.BigEndian.Uint16(body[0:2]),
+		Cmd:     body[2],
+		CmdArgs: body[3:],
+	}
+	if want := f.checksum(body); want != checksum {
+		return fmt.Sprintf("%s chk=BAD(got 0x%02x want 0x%02x)", f, checksum, want)
+	}
+	return f.String() + " chk=OK"
+}
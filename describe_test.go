@@ -0,0 +1,134 @@
+package lightswarm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandName(t *testing.T) {
+	assert.Equal(t, "FADE_RGB_TO_LEVEL", CommandName(FADE_RGB_TO_LEVEL))
+	assert.Equal(t, "ERASE_PSUEDO_ADDRESS_TABLE", CommandName(ERASE_PSUEDO_ADDRESS_TABLE))
+	assert.Equal(t, "0x2f", CommandName(0x2F))
+}
+
+func TestFrameString(t *testing.T) {
+	tt := []struct {
+		name     string
+		frame    Frame
+		expected string
+	}{
+		{
+			"on",
+			Frame{Addr: 690, Cmd: ON},
+			"690 ON",
+		},
+		{
+			"set level",
+			Frame{Addr: 690, Cmd: SET_LEVEL, CmdArgs: []byte{128}},
+			"690 SET_LEVEL level=128",
+		},
+		{
+			"fade",
+			Frame{Addr: 690, Cmd: FADE_TO_LEVEL, CmdArgs: Fade{Level: 255, Interval: 1, Step: 5}.Args()},
+			"690 FADE_TO_LEVEL fade=(255,1,5)",
+		},
+		{
+			"fade down",
+			Frame{Addr: 690, Cmd: FADE_DOWN, CmdArgs: []byte{0, 2, 3}},
+			"690 FADE_DOWN fade=(0,2,3)",
+		},
+		{
+			"set psuedo address",
+			Frame{Addr: 690, Cmd: SET_PSUEDO_ADDRESS, CmdArgs: []byte{0x03, 0xE8}},
+			"690 SET_PSUEDO_ADDRESS psuedo=1000",
+		},
+		{
+			"erase psuedo address table",
+			Frame{Addr: 690, Cmd: ERASE_PSUEDO_ADDRESS_TABLE},
+			"690 ERASE_PSUEDO_ADDRESS_TABLE",
+		},
+		{
+			"set rgb",
+			Frame{Addr: 690, Cmd: SET_RGB_LEVELS, CmdArgs: []byte{85, 199, 237}},
+			"690 SET_RGB_LEVELS r=85 g=199 b=237",
+		},
+		{
+			"fade rgb",
+			Frame{Addr: 690, Cmd: FADE_RGB_TO_LEVEL, CmdArgs: []byte{255, 1, 1, 0, 1, 1, 10, 2, 3}},
+			"690 FADE_RGB_TO_LEVEL r=(255,1,1) g=(0,1,1) b=(10,2,3)",
+		},
+		{
+			"fade multiple",
+			multipleFrames(t, 4095,
+				AddressFade{Addr: 690, Fade: Fade{Level: 255, Interval: 1, Step: 5}},
+				AddressFade{Addr: 227, Fade: Fade{Level: 0, Interval: 2, Step: 1}},
+			)[0],
+			"4095 FADE_MULTIPLE_TO_LEVEL 690=(255,1,5) 227=(0,2,1)",
+		},
+		{
+			"wrong argument count",
+			Frame{Addr: 690, Cmd: SET_RGB_LEVELS, CmdArgs: []byte{255, 1}},
+			"690 SET_RGB_LEVELS args=ff 01",
+		},
+		{
+			"missing arguments",
+			Frame{Addr: 690, Cmd: SET_LEVEL},
+			"690 SET_LEVEL args=none",
+		},
+		{
+			"unknown command",
+			Frame{Addr: 690, Cmd: 0x2F, CmdArgs: []byte{1, 2}},
+			"690 0x2f args=01 02",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.frame.String())
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tt := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{
+			"valid",
+			[]byte{0xC0, 2, 178, 0x20, 144, 0xC0},
+			"690 ON chk=OK",
+		},
+		{
+			"without end bytes",
+			[]byte{2, 178, 0x22, 128, 18},
+			"690 SET_LEVEL level=128 chk=OK",
+		},
+		{
+			"escaped",
+			Frame{Addr: 690, Cmd: SET_LEVEL, CmdArgs: []byte{END}}.Bytes(),
+			"690 SET_LEVEL level=192 chk=OK",
+		},
+		{
+			"bad checksum",
+			[]byte{0xC0, 2, 178, 0x20, 145, 0xC0},
+			"690 ON chk=BAD(got 0x91 want 0x90)",
+		},
+		{
+			"truncated",
+			[]byte{0xC0, 2, 178, 0xC0},
+			"invalid frame c0 02 b2 c0: lightswarm: truncated frame",
+		},
+		{
+			"bad escape",
+			[]byte{0xC0, 2, 178, ESC, 0x01, 0x20, 0xC0},
+			"invalid frame c0 02 b2 db 01 20 c0: lightswarm: unknown escape sequence",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Describe(tc.data))
+		})
+	}
+}
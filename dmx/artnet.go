@@ -0,0 +1,41 @@
+package dmx
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Default Art-Net UDP port
+const ArtNetPort = 6454
+
+// Art-Net packet constants
+var artNetID = []byte("Art-Net\x00")
+
+const (
+	artNetOpDmx       = 0x5000
+	artNetHeaderLen   = 18
+	artNetMinProtoVer = 14
+)
+
+// Parses an ArtDmx packet returning the 15 bit port address universe and
+// the channel data. ErrNotDMX is returned for other Art-Net packets.
+func ParseArtNet(p []byte) (uint16, []byte, error) {
+	if len(p) < 10 || !bytes.Equal(p[:8], artNetID) {
+		return 0, nil, ErrInvalidPacket
+	}
+	if binary.LittleEndian.Uint16(p[8:10]) != artNetOpDmx {
+		return 0, nil, ErrNotDMX
+	}
+	if len(p) < artNetHeaderLen {
+		return 0, nil, ErrInvalidPacket
+	}
+	if binary.BigEndian.Uint16(p[10:12]) < artNetMinProtoVer {
+		return 0, nil, ErrInvalidPacket
+	}
+	universe := uint16(p[15]&0x7F)<<8 | uint16(p[14])
+	length := int(binary.BigEndian.Uint16(p[16:18]))
+	if length > MaxChannels || len(p) < artNetHeaderLen+length {
+		return 0, nil, ErrInvalidPacket
+	}
+	return universe, p[artNetHeaderLen : artNetHeaderLen+length], nil
+}
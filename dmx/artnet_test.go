@@ -0,0 +1,87 @@
+package dmx
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Builds an ArtDmx packet
+func artDmx(universe uint16, data []byte) []byte {
+	p := append([]byte{}, artNetID...)
+	p = append(p, 0x00, 0x50) // OpDmx little endian
+	p = append(p, 0, 14)      // protocol version
+	p = append(p, 0, 0)       // sequence, physical
+	p = append(p, byte(universe), byte(universe>>8))
+	length := make([]byte, 2)
+	binary.BigEndian.PutUint16(length, uint16(len(data)))
+	p = append(p, length...)
+	return append(p, data...)
+}
+
+func TestParseArtNet(t *testing.T) {
+	poll := append(append([]byte{}, artNetID...), 0x00, 0x20, 0, 14)
+	oldVersion := artDmx(1, []byte{1})
+	oldVersion[11] = 13
+	short := artDmx(1, []byte{1, 2, 3})
+	short = short[:len(short)-1]
+	tt := []struct {
+		name     string
+		packet   []byte
+		universe uint16
+		data     []byte
+		err      error
+	}{
+		{
+			"dmx packet",
+			artDmx(1, []byte{255, 128, 0}),
+			1,
+			[]byte{255, 128, 0},
+			nil,
+		},
+		{
+			"net and sub-net universe",
+			artDmx(0x1234, []byte{1}),
+			0x1234,
+			[]byte{1},
+			nil,
+		},
+		{
+			"poll packet",
+			poll,
+			0,
+			nil,
+			ErrNotDMX,
+		},
+		{
+			"not art-net",
+			[]byte("hello world, this is not art-net"),
+			0,
+			nil,
+			ErrInvalidPacket,
+		},
+		{
+			"old protocol version",
+			oldVersion,
+			0,
+			nil,
+			ErrInvalidPacket,
+		},
+		{
+			"length longer than packet",
+			short,
+			0,
+			nil,
+			ErrInvalidPacket,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			universe, data, err := ParseArtNet(tc.packet)
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.universe, universe)
+			assert.Equal(t, tc.data, data)
+		})
+	}
+}
@@ -0,0 +1,235 @@
+/*
+Package dmx bridges DMX universes received over Art-Net or sACN (E1.31) to
+LightSwarm LED's.
+
+Channel ranges are mapped to addresses, a single channel drives the LED
+level with SET_LEVEL and three channels drive the Red, Green and Blue
+levels with SET_RGB_LEVELS. Consoles typically send every universe around
+44 times a second, far faster than the 38400 baud bus can carry a frame per
+fixture, so received data is coalesced and only changed values are sent,
+at most once per Interval.
+
+Universes are numbered from 1, as in sACN. Art-Net numbers its port
+addresses from 0, so Art-Net port address 0 is universe 1, port address 1
+is universe 2 and so on, matching the numbering most consoles display. A
+universe received over both protocols is a single universe, the most
+recent packet wins.
+
+	b := dmx.NewBridge(serialPort, []dmx.Mapping{
+		{Universe: 1, Channel: 1, Addr: 690, Mode: dmx.RGB},
+		{Universe: 1, Channel: 4, Addr: 227, Mode: dmx.Mono},
+	})
+	artnet, _ := net.ListenPacket("udp4", ":6454")
+	log.Fatal(b.Serve(ctx, artnet))
+*/
+package dmx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/thisissoon/lightswarm"
+)
+
+// Maximum number of channels in a DMX universe
+const MaxChannels = 512
+
+// Default interval between sending changed values
+const DefaultInterval = time.Millisecond * 50
+
+// Packet errors
+var (
+	ErrInvalidPacket = errors.New("dmx: invalid packet")
+	ErrNotDMX        = errors.New("dmx: packet does not contain dmx data")
+)
+
+// How a channel range maps onto a LightSwarm LED
+type Mode int
+
+// Mapping modes
+const (
+	Mono Mode = iota // 1 channel, SET_LEVEL
+	RGB              // 3 channels, SET_RGB_LEVELS
+)
+
+// Returns the number of channels used by the mode
+func (m Mode) channels() int {
+	if m == RGB {
+		return 3
+	}
+	return 1
+}
+
+// Maps a range of DMX channels to a LightSwarm address
+type Mapping struct {
+	Universe uint16 // Numbered from 1, Art-Net port address + 1
+	Channel  int    // First channel, 1-512
+	Addr     uint16
+	Mode     Mode
+}
+
+// Returns the mapped channel values from the universe data, channels
+// beyond the end of the data are 0
+func (m Mapping) values(data []byte) []byte {
+	values := make([]byte, m.Mode.channels())
+	for i := range values {
+		if c := m.Channel - 1 + i; c >= 0 && c < len(data) {
+			values[i] = data[c]
+		}
+	}
+	return values
+}
+
+// Returns the frame setting the values
+func (m Mapping) frame(values []byte) lightswarm.Frame {
+	cmd := lightswarm.SET_LEVEL
+	if m.Mode == RGB {
+		cmd = lightswarm.SET_RGB_LEVELS
+	}
+	return lightswarm.Frame{Addr: m.Addr, Cmd: cmd, CmdArgs: values}
+}
+
+// Bridges DMX universes to LightSwarm LED's written to Writer
+type Bridge struct {
+	// Exported Fields
+	Writer   io.Writer
+	Mappings []Mapping
+	Interval time.Duration // Minimum time between flushes, defaults to DefaultInterval
+
+	mu        sync.Mutex
+	universes map[uint16][]byte
+	sent      map[int][]byte // last values sent per mapping index
+}
+
+// Creates the universe and sent maps, so a Bridge built as a literal is
+// usable. Called with mu held.
+func (b *Bridge) init() {
+	if b.universes == nil {
+		b.universes = map[uint16][]byte{}
+	}
+	if b.sent == nil {
+		b.sent = map[int][]byte{}
+	}
+}
+
+// Returns the flush interval
+func (b *Bridge) interval() time.Duration {
+	if b.Interval <= 0 {
+		return DefaultInterval
+	}
+	return b.Interval
+}
+
+// Stores the latest data for a universe numbered from 1, it is sent on
+// the next Flush
+func (b *Bridge) Update(universe uint16, data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.init()
+	b.universes[universe] = append([]byte{}, data...)
+}
+
+// Parses an Art-Net or sACN packet and stores its data. Packets which do
+// not carry DMX data are ignored.
+func (b *Bridge) HandlePacket(p []byte) error {
+	universe, data, err := ParseArtNet(p)
+	if err == nil {
+		// Art-Net port addresses start at 0, sACN universes at 1
+		universe++
+	}
+	if err == ErrInvalidPacket {
+		universe, data, err = ParseSACN(p)
+	}
+	switch err {
+	case nil:
+		b.Update(universe, data)
+		return nil
+	case ErrNotDMX:
+		return nil
+	default:
+		return err
+	}
+}
+
+// Writes frames for every mapping whose values have changed since the
+// last flush, in a single write
+func (b *Bridge) Flush() (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.init()
+	out := []byte{}
+	changed := map[int][]byte{}
+	for i, m := range b.Mappings {
+		data, ok := b.universes[m.Universe]
+		if !ok {
+			continue
+		}
+		values := m.values(data)
+		if last, ok := b.sent[i]; ok && string(last) == string(values) {
+			continue
+		}
+		out = append(out, m.frame(values).Bytes()...)
+		changed[i] = values
+	}
+	if len(out) == 0 {
+		return 0, nil
+	}
+	n, err := b.Writer.Write(out)
+	if err != nil {
+		return n, err
+	}
+	for i, values := range changed {
+		b.sent[i] = values
+	}
+	return n, nil
+}
+
+// Reads packets from the connections and flushes changes every Interval
+// until the context is done or a write fails
+func (b *Bridge) Serve(ctx context.Context, conns ...net.PacketConn) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for _, conn := range conns {
+		go func(conn net.PacketConn) {
+			<-ctx.Done()
+			conn.Close()
+		}(conn)
+		go b.read(conn)
+	}
+	ticker := time.NewTicker(b.interval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := b.Flush(); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Reads packets from the connection until it is closed
+func (b *Bridge) read(conn net.PacketConn) {
+	buf := make([]byte, 1024)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		b.HandlePacket(buf[:n])
+	}
+}
+
+// Constructs a new Bridge writing to the given writer
+func NewBridge(writer io.Writer, mappings []Mapping) *Bridge {
+	return &Bridge{
+		Writer:   writer,
+		Mappings: mappings,
+	}
+}
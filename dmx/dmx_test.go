@@ -0,0 +1,216 @@
+package dmx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thisissoon/lightswarm"
+)
+
+// A buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte{}, b.buf.Bytes()...)
+}
+
+// A writer that always fails
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("boom")
+}
+
+// Returns the encoded bytes of the frames
+func frames(fs ...lightswarm.Frame) []byte {
+	b := []byte{}
+	for _, f := range fs {
+		b = append(b, f.Bytes()...)
+	}
+	return b
+}
+
+var mappings = []Mapping{
+	{Universe: 1, Channel: 1, Addr: 690, Mode: RGB},
+	{Universe: 1, Channel: 4, Addr: 227, Mode: Mono},
+	{Universe: 2, Channel: 512, Addr: 362, Mode: Mono},
+}
+
+func TestMappingValues(t *testing.T) {
+	tt := []struct {
+		name     string
+		mapping  Mapping
+		data     []byte
+		expected []byte
+	}{
+		{"mono", Mapping{Channel: 2, Mode: Mono}, []byte{1, 2, 3}, []byte{2}},
+		{"rgb", Mapping{Channel: 1, Mode: RGB}, []byte{1, 2, 3}, []byte{1, 2, 3}},
+		{"beyond data", Mapping{Channel: 3, Mode: RGB}, []byte{1, 2, 3}, []byte{3, 0, 0}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.mapping.values(tc.data))
+		})
+	}
+}
+
+func TestBridgeFlush(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	b := NewBridge(buff, mappings)
+	// nothing received yet
+	n, err := b.Flush()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+	// first universe received
+	b.Update(1, []byte{255, 128, 0, 64})
+	_, err = b.Flush()
+	assert.Nil(t, err)
+	assert.Equal(t, frames(
+		lightswarm.Frame{Addr: 690, Cmd: lightswarm.SET_RGB_LEVELS, CmdArgs: []byte{255, 128, 0}},
+		lightswarm.Frame{Addr: 227, Cmd: lightswarm.SET_LEVEL, CmdArgs: []byte{64}},
+	), buff.Bytes())
+	// many updates between flushes are coalesced, only changes are sent
+	buff.Reset()
+	for i := 0; i < 10; i++ {
+		b.Update(1, []byte{255, 128, 0, byte(i)})
+	}
+	b.Update(2, make([]byte, 512))
+	_, err = b.Flush()
+	assert.Nil(t, err)
+	assert.Equal(t, frames(
+		lightswarm.Frame{Addr: 227, Cmd: lightswarm.SET_LEVEL, CmdArgs: []byte{9}},
+		lightswarm.Frame{Addr: 362, Cmd: lightswarm.SET_LEVEL, CmdArgs: []byte{0}},
+	), buff.Bytes())
+	// no changes
+	buff.Reset()
+	b.Update(1, []byte{255, 128, 0, 9})
+	n, err = b.Flush()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestBridgeZeroValue(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	b := &Bridge{Writer: buff, Mappings: mappings}
+	b.Update(1, []byte{255, 128, 0, 64})
+	_, err := b.Flush()
+	assert.Nil(t, err)
+	assert.Equal(t, frames(
+		lightswarm.Frame{Addr: 690, Cmd: lightswarm.SET_RGB_LEVELS, CmdArgs: []byte{255, 128, 0}},
+		lightswarm.Frame{Addr: 227, Cmd: lightswarm.SET_LEVEL, CmdArgs: []byte{64}},
+	), buff.Bytes())
+}
+
+func TestBridgeFlushError(t *testing.T) {
+	b := NewBridge(errWriter{}, mappings)
+	b.Update(1, []byte{1})
+	_, err := b.Flush()
+	assert.EqualError(t, err, "boom")
+	// values are resent after a failed write
+	buff := bytes.NewBuffer(nil)
+	b.Writer = buff
+	b.Flush()
+	assert.NotEmpty(t, buff.Bytes())
+}
+
+func TestBridgeHandlePacket(t *testing.T) {
+	tt := []struct {
+		name     string
+		packet   []byte
+		expected []byte
+		err      error
+	}{
+		{
+			"art-net port address 0 is universe 1",
+			artDmx(0, []byte{0, 0, 0, 10}),
+			frames(
+				lightswarm.Frame{Addr: 690, Cmd: lightswarm.SET_RGB_LEVELS, CmdArgs: []byte{0, 0, 0}},
+				lightswarm.Frame{Addr: 227, Cmd: lightswarm.SET_LEVEL, CmdArgs: []byte{10}},
+			),
+			nil,
+		},
+		{
+			"art-net port address 1 is universe 2",
+			artDmx(1, make([]byte, 512)),
+			frames(lightswarm.Frame{Addr: 362, Cmd: lightswarm.SET_LEVEL, CmdArgs: []byte{0}}),
+			nil,
+		},
+		{
+			"sacn",
+			sacn(1, 0, []byte{0, 0, 0, 10}),
+			frames(
+				lightswarm.Frame{Addr: 690, Cmd: lightswarm.SET_RGB_LEVELS, CmdArgs: []byte{0, 0, 0}},
+				lightswarm.Frame{Addr: 227, Cmd: lightswarm.SET_LEVEL, CmdArgs: []byte{10}},
+			),
+			nil,
+		},
+		{
+			"ignored packet",
+			sacn(1, 0xDD, []byte{1}),
+			nil,
+			nil,
+		},
+		{
+			"invalid packet",
+			[]byte("nope"),
+			nil,
+			ErrInvalidPacket,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			buff := bytes.NewBuffer(nil)
+			b := NewBridge(buff, mappings)
+			assert.Equal(t, tc.err, b.HandlePacket(tc.packet))
+			b.Flush()
+			assert.Equal(t, tc.expected, buff.Bytes())
+		})
+	}
+}
+
+func TestBridgeServe(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buff := &syncBuffer{}
+	b := NewBridge(buff, mappings)
+	b.Interval = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- b.Serve(ctx, conn) }()
+	sender, err := net.Dial("udp4", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	expected := frames(lightswarm.Frame{Addr: 227, Cmd: lightswarm.SET_LEVEL, CmdArgs: []byte{10}})
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) && len(buff.Bytes()) == 0 {
+		sender.Write(artDmx(0, []byte{0, 0, 0, 10}))
+		time.Sleep(time.Millisecond * 5)
+	}
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+	assert.Equal(t, frames(
+		lightswarm.Frame{Addr: 690, Cmd: lightswarm.SET_RGB_LEVELS, CmdArgs: []byte{0, 0, 0}},
+	), buff.Bytes()[:9])
+	assert.Equal(t, expected, buff.Bytes()[9:])
+}
@@ -0,0 +1,58 @@
+package dmx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+)
+
+// Default sACN (E1.31) UDP port
+const SACNPort = 5568
+
+// sACN packet constants
+var sacnID = []byte("ASC-E1.17\x00\x00\x00")
+
+const (
+	sacnRootVector     = 0x00000004
+	sacnFramingVector  = 0x00000002
+	sacnDMPVector      = 0x02
+	sacnHeaderLen      = 126 // up to and including the start code
+	sacnOptionsOffset  = 112
+	sacnPreviewData    = 0x80
+	sacnStreamTerminal = 0x40
+)
+
+// Parses an E1.31 data packet returning the universe and the channel data.
+// ErrNotDMX is returned for packets with a non-zero start code, preview
+// data or stream termination.
+func ParseSACN(p []byte) (uint16, []byte, error) {
+	if len(p) < sacnHeaderLen || !bytes.Equal(p[4:16], sacnID) {
+		return 0, nil, ErrInvalidPacket
+	}
+	if binary.BigEndian.Uint32(p[18:22]) != sacnRootVector ||
+		binary.BigEndian.Uint32(p[40:44]) != sacnFramingVector ||
+		p[117] != sacnDMPVector {
+		return 0, nil, ErrNotDMX
+	}
+	if p[sacnOptionsOffset]&(sacnPreviewData|sacnStreamTerminal) != 0 {
+		return 0, nil, ErrNotDMX
+	}
+	universe := binary.BigEndian.Uint16(p[113:115])
+	// the property value count includes the start code
+	count := int(binary.BigEndian.Uint16(p[123:125]))
+	if count < 1 || count > MaxChannels+1 || len(p) < sacnHeaderLen-1+count {
+		return 0, nil, ErrInvalidPacket
+	}
+	if p[125] != 0 {
+		return 0, nil, ErrNotDMX
+	}
+	return universe, p[sacnHeaderLen : sacnHeaderLen-1+count], nil
+}
+
+// Returns the multicast group address for an sACN universe
+func SACNMulticastAddr(universe uint16) *net.UDPAddr {
+	return &net.UDPAddr{
+		IP:   net.IPv4(239, 255, byte(universe>>8), byte(universe)),
+		Port: SACNPort,
+	}
+}
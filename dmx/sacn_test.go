@@ -0,0 +1,99 @@
+package dmx
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Builds an E1.31 data packet
+func sacn(universe uint16, startCode byte, data []byte) []byte {
+	p := make([]byte, sacnHeaderLen+len(data))
+	binary.BigEndian.PutUint16(p[0:2], 0x0010)
+	copy(p[4:16], sacnID)
+	binary.BigEndian.PutUint32(p[18:22], sacnRootVector)
+	binary.BigEndian.PutUint32(p[40:44], sacnFramingVector)
+	copy(p[44:], "console")
+	p[108] = 100 // priority
+	binary.BigEndian.PutUint16(p[113:115], universe)
+	p[117] = sacnDMPVector
+	p[118] = 0xA1
+	binary.BigEndian.PutUint16(p[121:123], 1)
+	binary.BigEndian.PutUint16(p[123:125], uint16(len(data)+1))
+	p[125] = startCode
+	copy(p[126:], data)
+	return p
+}
+
+func TestParseSACN(t *testing.T) {
+	preview := sacn(1, 0, []byte{1})
+	preview[sacnOptionsOffset] = sacnPreviewData
+	sync := sacn(1, 0, []byte{1})
+	binary.BigEndian.PutUint32(sync[18:22], 0x00000008)
+	tt := []struct {
+		name     string
+		packet   []byte
+		universe uint16
+		data     []byte
+		err      error
+	}{
+		{
+			"dmx packet",
+			sacn(7, 0, []byte{255, 128, 0}),
+			7,
+			[]byte{255, 128, 0},
+			nil,
+		},
+		{
+			"alternate start code",
+			sacn(7, 0xDD, []byte{1}),
+			0,
+			nil,
+			ErrNotDMX,
+		},
+		{
+			"preview data",
+			preview,
+			0,
+			nil,
+			ErrNotDMX,
+		},
+		{
+			"extended packet",
+			sync,
+			0,
+			nil,
+			ErrNotDMX,
+		},
+		{
+			"too short",
+			sacn(7, 0, nil)[:100],
+			0,
+			nil,
+			ErrInvalidPacket,
+		},
+		{
+			"count longer than packet",
+			sacn(7, 0, []byte{1, 2, 3})[:127],
+			0,
+			nil,
+			ErrInvalidPacket,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			universe, data, err := ParseSACN(tc.packet)
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.universe, universe)
+			assert.Equal(t, tc.data, data)
+		})
+	}
+}
+
+func TestSACNMulticastAddr(t *testing.T) {
+	addr := SACNMulticastAddr(0x0102)
+	assert.True(t, net.IPv4(239, 255, 1, 2).Equal(addr.IP))
+	assert.Equal(t, SACNPort, addr.Port)
+}
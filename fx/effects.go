@@ -0,0 +1,118 @@
+package fx
+
+import (
+	"math"
+	"time"
+
+	"github.com/thisissoon/lightswarm"
+)
+
+// Interval between the random levels of a candle flicker
+const flickerInterval = time.Millisecond * 100
+
+// Returns how far through the period t is, from 0 up to 1
+func phase(t, period time.Duration) float64 {
+	if period <= 0 {
+		return 0
+	}
+	t %= period
+	if t < 0 {
+		t += period
+	}
+	return float64(t) / float64(period)
+}
+
+// Returns the colour scaled by f, from 0 to 1
+func scale(c [3]byte, f float64) [3]byte {
+	f = math.Max(0, math.Min(1, f))
+	return [3]byte{
+		byte(math.Floor(float64(c[0])*f + 0.5)),
+		byte(math.Floor(float64(c[1])*f + 0.5)),
+		byte(math.Floor(float64(c[2])*f + 0.5)),
+	}
+}
+
+// Returns the fully saturated colour with the hue, from 0 up to 1
+func hue(h float64) [3]byte {
+	c := lightswarm.HSV(h*360, 1, 1)
+	return [3]byte{c.R, c.G, c.B}
+}
+
+// Returns a pseudo random number from 0 up to 1 for the integers
+func noise(a, b int64) float64 {
+	h := uint64(a)*0x9E3779B97F4A7C15 ^ uint64(b)*0xC2B2AE3D27D4EB4F
+	h ^= h >> 33
+	h *= 0xFF51AFD7ED558CCD
+	h ^= h >> 33
+	return float64(h>>11) / (1 << 53)
+}
+
+// Holds a single colour on every LED
+func Solid(c [3]byte) Effect {
+	return EffectFunc(func(t time.Duration, i, n int) [3]byte {
+		return c
+	})
+}
+
+// Lights width consecutive LED's in the colour, moving along the addresses
+// once per period
+func Chase(c [3]byte, width int, period time.Duration) Effect {
+	return EffectFunc(func(t time.Duration, i, n int) [3]byte {
+		head := int(phase(t, period) * float64(n))
+		if (i-head+n)%n < width {
+			return c
+		}
+		return [3]byte{}
+	})
+}
+
+// Flashes the colour at the start of every period, fading linearly to off
+func Pulse(c [3]byte, period time.Duration) Effect {
+	return EffectFunc(func(t time.Duration, i, n int) [3]byte {
+		return scale(c, 1-phase(t, period))
+	})
+}
+
+// Fades the colour smoothly up from off and back once per period
+func Breathe(c [3]byte, period time.Duration) Effect {
+	return EffectFunc(func(t time.Duration, i, n int) [3]byte {
+		return scale(c, (1-math.Cos(2*math.Pi*phase(t, period)))/2)
+	})
+}
+
+// Cycles through every hue once per period, spreading the spectrum across
+// the LED's
+func Rainbow(period time.Duration) Effect {
+	return EffectFunc(func(t time.Duration, i, n int) [3]byte {
+		return hue(phase(t, period) + float64(i)/float64(n))
+	})
+}
+
+// Turns the colour on for the duty fraction of every period
+func Strobe(c [3]byte, period time.Duration, duty float64) Effect {
+	return EffectFunc(func(t time.Duration, i, n int) [3]byte {
+		if phase(t, period) < duty {
+			return c
+		}
+		return [3]byte{}
+	})
+}
+
+// Flickers the colour between half and full brightness like a candle
+// flame, each LED flickering independently
+func Candle(c [3]byte) Effect {
+	return EffectFunc(func(t time.Duration, i, n int) [3]byte {
+		k := int64(t / flickerInterval)
+		f := phase(t, flickerInterval)
+		a, b := noise(int64(i), k), noise(int64(i), k+1)
+		return scale(c, 0.5+0.5*(a+(b-a)*f))
+	})
+}
+
+// Delays the effect on each LED by delay times its position, so a single
+// colour effect ripples along the addresses
+func Spread(effect Effect, delay time.Duration) Effect {
+	return EffectFunc(func(t time.Duration, i, n int) [3]byte {
+		return effect.Render(t-delay*time.Duration(i), i, n)
+	})
+}
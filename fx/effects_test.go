@@ -0,0 +1,65 @@
+package fx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var red = [3]byte{255, 0, 0}
+
+// Renders the effect for n LED's at t
+func render(e Effect, t time.Duration, n int) [][3]byte {
+	colors := make([][3]byte, n)
+	for i := range colors {
+		colors[i] = e.Render(t, i, n)
+	}
+	return colors
+}
+
+func TestEffects(t *testing.T) {
+	off := [3]byte{}
+	tt := []struct {
+		name     string
+		effect   Effect
+		t        time.Duration
+		expected [][3]byte
+	}{
+		{"solid", Solid(red), time.Hour, [][3]byte{red, red}},
+		{"chase start", Chase(red, 1, time.Second*4), 0, [][3]byte{red, off, off, off}},
+		{"chase moved", Chase(red, 2, time.Second*4), time.Second * 3, [][3]byte{red, off, off, red}},
+		{"chase next period", Chase(red, 1, time.Second*4), time.Second * 5, [][3]byte{off, red, off, off}},
+		{"pulse start", Pulse(red, time.Second), 0, [][3]byte{red}},
+		{"pulse half", Pulse(red, time.Second), time.Millisecond * 500, [][3]byte{{128, 0, 0}}},
+		{"breathe start", Breathe(red, time.Second), 0, [][3]byte{off}},
+		{"breathe peak", Breathe(red, time.Second), time.Millisecond * 500, [][3]byte{red}},
+		{"breathe quarter", Breathe(red, time.Second), time.Millisecond * 250, [][3]byte{{127, 0, 0}}},
+		{"rainbow start", Rainbow(time.Second), 0, [][3]byte{red, {0, 255, 255}}},
+		{"rainbow third", Rainbow(time.Second * 3), time.Second, [][3]byte{{0, 255, 0}, {255, 0, 255}}},
+		{"strobe on", Strobe(red, time.Second, 0.1), time.Millisecond * 50, [][3]byte{red}},
+		{"strobe off", Strobe(red, time.Second, 0.1), time.Millisecond * 150, [][3]byte{off}},
+		{"spread", Spread(Strobe(red, time.Second, 0.5), time.Millisecond*400), time.Millisecond * 100, [][3]byte{red, off, red}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, render(tc.effect, tc.t, len(tc.expected)))
+		})
+	}
+}
+
+func TestCandle(t *testing.T) {
+	c := Candle(red)
+	for ms := 0; ms < 2000; ms += 7 {
+		at := time.Duration(ms) * time.Millisecond
+		colors := render(c, at, 3)
+		for _, color := range colors {
+			assert.True(t, color[0] >= 127, "%v at %s", color, at)
+			assert.Equal(t, byte(0), color[1])
+		}
+		// deterministic
+		assert.Equal(t, colors, render(c, at, 3))
+	}
+	// LED's flicker independently
+	assert.NotEqual(t, c.Render(time.Second, 0, 2), c.Render(time.Second, 1, 2))
+}
@@ -0,0 +1,296 @@
+/*
+Package fx renders time based effects onto LightSwarm LED's.
+
+An Effect returns the colour of each LED at a point in time. Effects are
+added to an Engine as layers, each driving its own set of addresses, and
+the engine renders every layer at a fixed tick rate, writing SET_RGB_LEVELS
+or SET_LEVEL frames for the LED's whose value has changed:
+
+	e := fx.NewEngine(bus)
+	e.Add(&fx.Layer{
+		Effect: fx.Rainbow(time.Second * 5),
+		Addrs:  []uint16{690, 691, 692, 693},
+	})
+	candle := &fx.Layer{
+		Effect: fx.Candle([3]byte{255, 147, 41}),
+		Addrs:  []uint16{227},
+	}
+	e.Add(candle)
+	e.Start()
+	defer e.Stop()
+
+A SET_RGB_LEVELS frame takes around 2ms on the wire at 38400 baud, so the
+number of LED's which can change every tick is limited by the tick rate.
+*/
+package fx
+
+import (
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/thisissoon/lightswarm"
+)
+
+// Default interval between rendered frames, 25 frames a second
+const DefaultRate = time.Millisecond * 40
+
+// Layer errors
+var (
+	ErrAddressInUse = errors.New("fx: address is used by another layer")
+	ErrNotAdded     = errors.New("fx: layer is not added to an engine")
+)
+
+// Returns the colour of LED i of n at time t since the effect started
+type Effect interface {
+	Render(t time.Duration, i, n int) [3]byte
+}
+
+// Adapts a function to the Effect interface
+type EffectFunc func(t time.Duration, i, n int) [3]byte
+
+// Calls f
+func (f EffectFunc) Render(t time.Duration, i, n int) [3]byte {
+	return f(t, i, n)
+}
+
+// An effect driving a set of addresses. Fields must not be changed once
+// the layer is added to an engine.
+type Layer struct {
+	// Exported Fields
+	Effect Effect
+	Addrs  []uint16
+	Mono   bool // Send SET_LEVEL with the brightest channel instead of SET_RGB_LEVELS
+
+	engine  *Engine
+	started time.Time     // when the layer was last started or resumed
+	elapsed time.Duration // effect time accumulated before started
+	paused  bool
+}
+
+// Returns the effect time at now
+func (layer *Layer) at(now time.Time) time.Duration {
+	if layer.paused {
+		return layer.elapsed
+	}
+	return layer.elapsed + now.Sub(layer.started)
+}
+
+// Returns the frame setting the colour of the address
+func (layer *Layer) frame(addr uint16, c [3]byte) lightswarm.Frame {
+	if layer.Mono {
+		level := c[0]
+		if c[1] > level {
+			level = c[1]
+		}
+		if c[2] > level {
+			level = c[2]
+		}
+		return lightswarm.Frame{Addr: addr, Cmd: lightswarm.SET_LEVEL, CmdArgs: []byte{level}}
+	}
+	return lightswarm.Frame{Addr: addr, Cmd: lightswarm.SET_RGB_LEVELS, CmdArgs: c[:]}
+}
+
+// Freezes the effect, LED's hold their current colour until resumed
+func (layer *Layer) Pause() error {
+	e := layer.engine
+	if e == nil {
+		return ErrNotAdded
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !layer.paused {
+		layer.elapsed = layer.at(e.now())
+		layer.paused = true
+	}
+	return nil
+}
+
+// Continues a paused effect from where it was paused
+func (layer *Layer) Resume() error {
+	e := layer.engine
+	if e == nil {
+		return ErrNotAdded
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if layer.paused {
+		layer.started = e.now()
+		layer.paused = false
+	}
+	return nil
+}
+
+// Removes the layer from its engine, LED's are left at their current
+// colour
+func (layer *Layer) Remove() error {
+	e := layer.engine
+	if e == nil {
+		return ErrNotAdded
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, l := range e.layers {
+		if l == layer {
+			e.layers = append(e.layers[:i], e.layers[i+1:]...)
+			break
+		}
+	}
+	for _, addr := range layer.Addrs {
+		delete(e.sent, addr)
+	}
+	layer.engine = nil
+	return nil
+}
+
+// Renders layers at a fixed rate, writing the frames to Writer
+type Engine struct {
+	// Exported Fields
+	Writer   io.Writer
+	Rate     time.Duration // Interval between ticks, defaults to DefaultRate
+	ErrorLog *log.Logger   // Logs write errors while running, defaults to the log package
+
+	mu     sync.Mutex
+	layers []*Layer
+	sent   map[uint16][]byte // last arguments sent per address
+	stop   chan struct{}
+	done   chan struct{}
+	now    func() time.Time
+}
+
+// Creates the sent map and defaults the clock, so an Engine built as a
+// literal is usable. Called with mu held.
+func (e *Engine) init() {
+	if e.sent == nil {
+		e.sent = map[uint16][]byte{}
+	}
+	if e.now == nil {
+		e.now = time.Now
+	}
+}
+
+// Returns the tick interval
+func (e *Engine) rate() time.Duration {
+	if e.Rate <= 0 {
+		return DefaultRate
+	}
+	return e.Rate
+}
+
+// Logs to ErrorLog or the standard logger
+func (e *Engine) logf(format string, args ...interface{}) {
+	if e.ErrorLog != nil {
+		e.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// Adds a layer, starting its effect. Layers may not share addresses.
+func (e *Engine) Add(layer *Layer) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.init()
+	used := map[uint16]bool{}
+	for _, l := range e.layers {
+		for _, addr := range l.Addrs {
+			used[addr] = true
+		}
+	}
+	for _, addr := range layer.Addrs {
+		if used[addr] {
+			return ErrAddressInUse
+		}
+	}
+	layer.engine = e
+	layer.started = e.now()
+	layer.elapsed = 0
+	layer.paused = false
+	e.layers = append(e.layers, layer)
+	return nil
+}
+
+// Renders every layer and writes the frames for LED's which have changed
+// since the last tick in a single write
+func (e *Engine) Tick() (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.init()
+	now := e.now()
+	out := []byte{}
+	changed := map[uint16][]byte{}
+	for _, layer := range e.layers {
+		t := layer.at(now)
+		for i, addr := range layer.Addrs {
+			frame := layer.frame(addr, layer.Effect.Render(t, i, len(layer.Addrs)))
+			if last, ok := e.sent[addr]; ok && string(last) == string(frame.CmdArgs) {
+				continue
+			}
+			out = append(out, frame.Bytes()...)
+			changed[addr] = append([]byte{}, frame.CmdArgs...)
+		}
+	}
+	if len(out) == 0 {
+		return 0, nil
+	}
+	n, err := e.Writer.Write(out)
+	if err != nil {
+		return n, err
+	}
+	for addr, args := range changed {
+		e.sent[addr] = args
+	}
+	return n, nil
+}
+
+// Starts ticking in the background, does nothing if already started
+func (e *Engine) Start() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stop != nil {
+		return
+	}
+	e.stop = make(chan struct{})
+	e.done = make(chan struct{})
+	go e.run(e.stop, e.done)
+}
+
+// Ticks until stopped
+func (e *Engine) run(stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(e.rate())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := e.Tick(); err != nil {
+				e.logf("fx: %s", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Stops ticking, waiting for an in progress tick to finish. LED's are
+// left at their current colour.
+func (e *Engine) Stop() {
+	e.mu.Lock()
+	stop, done := e.stop, e.done
+	e.stop, e.done = nil, nil
+	e.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Constructs a new Engine writing to the given writer
+func NewEngine(writer io.Writer) *Engine {
+	return &Engine{
+		Writer: writer,
+	}
+}
@@ -0,0 +1,185 @@
+package fx
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thisissoon/lightswarm"
+)
+
+// A controllable clock
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// A buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len()
+}
+
+// A writer that always fails
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("boom")
+}
+
+// Returns a new engine using a fake clock
+func newTestEngine(w *bytes.Buffer) (*Engine, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	e := NewEngine(w)
+	e.now = clock.now
+	return e, clock
+}
+
+// Returns the encoded SET_RGB_LEVELS frame
+func rgb(addr uint16, c [3]byte) []byte {
+	return lightswarm.Frame{Addr: addr, Cmd: lightswarm.SET_RGB_LEVELS, CmdArgs: c[:]}.Bytes()
+}
+
+func TestEngineTick(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	e, clock := newTestEngine(buff)
+	assert.Nil(t, e.Add(&Layer{
+		Effect: Chase(red, 1, time.Second*2),
+		Addrs:  []uint16{1, 2},
+	}))
+	assert.Nil(t, e.Add(&Layer{
+		Effect: Strobe([3]byte{0, 0, 100}, time.Second, 0.5),
+		Addrs:  []uint16{3},
+		Mono:   true,
+	}))
+	_, err := e.Tick()
+	assert.Nil(t, err)
+	expected := append(rgb(1, red), rgb(2, [3]byte{})...)
+	expected = append(expected, lightswarm.Frame{Addr: 3, Cmd: lightswarm.SET_LEVEL, CmdArgs: []byte{100}}.Bytes()...)
+	assert.Equal(t, expected, buff.Bytes())
+	// nothing changed
+	buff.Reset()
+	clock.advance(time.Millisecond * 100)
+	n, err := e.Tick()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+	// only changed LED's are sent
+	clock.advance(time.Millisecond * 900)
+	e.Tick()
+	expected = append(rgb(1, [3]byte{}), rgb(2, red)...)
+	assert.Equal(t, expected, buff.Bytes())
+}
+
+func TestEngineAddressInUse(t *testing.T) {
+	e, _ := newTestEngine(bytes.NewBuffer(nil))
+	assert.Nil(t, e.Add(&Layer{Effect: Solid(red), Addrs: []uint16{1, 2}}))
+	assert.Equal(t, ErrAddressInUse, e.Add(&Layer{Effect: Solid(red), Addrs: []uint16{2, 3}}))
+	assert.Nil(t, e.Add(&Layer{Effect: Solid(red), Addrs: []uint16{3}}))
+}
+
+func TestLayerPause(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	e, clock := newTestEngine(buff)
+	layer := &Layer{Effect: Pulse(red, time.Second), Addrs: []uint16{1}}
+	assert.Equal(t, ErrNotAdded, layer.Pause())
+	e.Add(layer)
+	clock.advance(time.Millisecond * 500)
+	assert.Nil(t, layer.Pause())
+	clock.advance(time.Hour)
+	e.Tick()
+	assert.Equal(t, rgb(1, [3]byte{128, 0, 0}), buff.Bytes())
+	// resumes where it was paused
+	buff.Reset()
+	assert.Nil(t, layer.Resume())
+	clock.advance(time.Millisecond * 250)
+	e.Tick()
+	assert.Equal(t, rgb(1, [3]byte{64, 0, 0}), buff.Bytes())
+}
+
+func TestLayerRemove(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	e, _ := newTestEngine(buff)
+	layer := &Layer{Effect: Solid(red), Addrs: []uint16{1}}
+	e.Add(layer)
+	e.Tick()
+	assert.Nil(t, layer.Remove())
+	assert.Equal(t, ErrNotAdded, layer.Remove())
+	buff.Reset()
+	e.Tick()
+	assert.Empty(t, buff.Bytes())
+	// the address can be reused and is sent again
+	assert.Nil(t, e.Add(&Layer{Effect: Solid(red), Addrs: []uint16{1}}))
+	e.Tick()
+	assert.Equal(t, rgb(1, red), buff.Bytes())
+}
+
+func TestEngineZeroValue(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	e := &Engine{Writer: buff}
+	assert.Nil(t, e.Add(&Layer{Effect: Solid(red), Addrs: []uint16{1}}))
+	e.Tick()
+	e.Tick()
+	assert.Equal(t, rgb(1, red), buff.Bytes())
+	// ticking before a layer is added writes nothing
+	n, err := (&Engine{Writer: buff}).Tick()
+	assert.Equal(t, 0, n)
+	assert.Nil(t, err)
+}
+
+func TestEngineTickError(t *testing.T) {
+	e := NewEngine(errWriter{})
+	e.Add(&Layer{Effect: Solid(red), Addrs: []uint16{1}})
+	_, err := e.Tick()
+	assert.EqualError(t, err, "boom")
+	// values are resent after a failed write
+	buff := bytes.NewBuffer(nil)
+	e.Writer = buff
+	e.Tick()
+	assert.Equal(t, rgb(1, red), buff.Bytes())
+}
+
+func TestEngineStartStop(t *testing.T) {
+	buff := &syncBuffer{}
+	e := NewEngine(buff)
+	e.Rate = time.Millisecond
+	e.Add(&Layer{Effect: Strobe(red, time.Millisecond*2, 0.5), Addrs: []uint16{1}})
+	e.Start()
+	e.Start()
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) && buff.Len() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	e.Stop()
+	e.Stop()
+	n := buff.Len()
+	assert.True(t, n > 0)
+	time.Sleep(time.Millisecond * 10)
+	assert.Equal(t, n, buff.Len())
+}
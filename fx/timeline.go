@@ -0,0 +1,91 @@
+package fx
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// A colour at a point in time
+type Keyframe struct {
+	At    time.Duration
+	Color [3]byte
+}
+
+// Interpolates linearly between keyframes, holding the first colour before
+// the first keyframe and the last colour after the last
+type Keyframes struct {
+	Frames []Keyframe
+	Loop   bool // Restart from 0 after the last keyframe
+}
+
+// Returns the interpolated colour at t, the same for every LED
+func (k Keyframes) Render(t time.Duration, i, n int) [3]byte {
+	frames := append([]Keyframe{}, k.Frames...)
+	if len(frames) == 0 {
+		return [3]byte{}
+	}
+	sort.SliceStable(frames, func(a, b int) bool {
+		return frames[a].At < frames[b].At
+	})
+	last := frames[len(frames)-1]
+	if k.Loop && last.At > 0 {
+		t %= last.At
+		if t < 0 {
+			t += last.At
+		}
+	}
+	if t <= frames[0].At {
+		return frames[0].Color
+	}
+	for j := 1; j < len(frames); j++ {
+		a, b := frames[j-1], frames[j]
+		if t > b.At {
+			continue
+		}
+		f := float64(t-a.At) / float64(b.At-a.At)
+		var c [3]byte
+		for ch := range c {
+			from, to := float64(a.Color[ch]), float64(b.Color[ch])
+			c[ch] = byte(math.Floor(from + (to-from)*f + 0.5))
+		}
+		return c
+	}
+	return last.Color
+}
+
+// An effect played for a duration
+type Step struct {
+	Effect   Effect
+	Duration time.Duration
+}
+
+// Plays effects one after another, each starting from 0. The last effect
+// continues indefinitely unless the timeline loops.
+type Timeline struct {
+	Steps []Step
+	Loop  bool // Restart from the first step after the last
+}
+
+// Renders the step playing at t
+func (tl Timeline) Render(t time.Duration, i, n int) [3]byte {
+	if len(tl.Steps) == 0 {
+		return [3]byte{}
+	}
+	if tl.Loop {
+		var total time.Duration
+		for _, s := range tl.Steps {
+			total += s.Duration
+		}
+		if total > 0 {
+			t %= total
+		}
+	}
+	for j, s := range tl.Steps {
+		if t < s.Duration || j == len(tl.Steps)-1 {
+			return s.Effect.Render(t, i, n)
+		}
+		t -= s.Duration
+	}
+	return [3]byte{}
+}
@@ -0,0 +1,69 @@
+package fx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyframes(t *testing.T) {
+	k := Keyframes{
+		Frames: []Keyframe{
+			{At: time.Second * 2, Color: [3]byte{0, 0, 255}},
+			{At: 0, Color: [3]byte{255, 0, 0}},
+			{At: time.Second, Color: [3]byte{0, 255, 0}},
+		},
+	}
+	loop := k
+	loop.Loop = true
+	tt := []struct {
+		name      string
+		keyframes Keyframes
+		t         time.Duration
+		expected  [3]byte
+	}{
+		{"before first", k, -time.Second, [3]byte{255, 0, 0}},
+		{"first", k, 0, [3]byte{255, 0, 0}},
+		{"between", k, time.Millisecond * 500, [3]byte{128, 128, 0}},
+		{"keyframe", k, time.Second, [3]byte{0, 255, 0}},
+		{"after last", k, time.Second * 5, [3]byte{0, 0, 255}},
+		{"loop", loop, time.Millisecond * 4500, [3]byte{128, 128, 0}},
+		{"empty", Keyframes{}, time.Second, [3]byte{}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.keyframes.Render(tc.t, 0, 1))
+		})
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	green := [3]byte{0, 255, 0}
+	tl := Timeline{
+		Steps: []Step{
+			{Effect: Solid(red), Duration: time.Second},
+			{Effect: Pulse(green, time.Second*2), Duration: time.Second * 2},
+		},
+	}
+	loop := tl
+	loop.Loop = true
+	tt := []struct {
+		name     string
+		timeline Timeline
+		t        time.Duration
+		expected [3]byte
+	}{
+		{"first step", tl, time.Millisecond * 500, red},
+		{"second step starts from 0", tl, time.Second, green},
+		{"second step", tl, time.Second * 2, [3]byte{0, 128, 0}},
+		{"last step continues", tl, time.Second * 5, green},
+		{"loop", loop, time.Second * 3, red},
+		{"empty", Timeline{}, time.Second, [3]byte{}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.timeline.Render(tc.t, 0, 1))
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package lightswarm
+
+import (
+	"context"
+	"io"
+)
+
+// Ensure LED and Group share the same command set
+var (
+	_ ContextLight = (*LED)(nil)
+	_ ContextLight = (*Group)(nil)
+)
+
+// Represents a group of Lightswarm LED's sharing a psuedo address,
+// commands sent to a group are received by every member in one frame.
+// LED's are added to a group with LED.SetPseudoAddress or LED.Join.
+type Group struct {
+	// Exported Fields
+	Addr   uint16
+	Writer io.Writer
+}
+
+// Returns an LED targeting the group psuedo address
+func (group *Group) led() *LED {
+	return &LED{Addr: group.Addr, Writer: group.Writer}
+}
+
+// Send a typed command to the group
+func (group *Group) Send(cmd Command) (int, []byte, error) {
+	return group.SendContext(context.Background(), cmd)
+}
+
+// As Send but abandoned if the context is done before it is written
+func (group *Group) SendContext(ctx context.Context, cmd Command) (int, []byte, error) {
+	return group.led().SendContext(ctx, cmd)
+}
+
+// Send the On command to the group
+func (group *Group) On() (int, []byte, error) {
+	return group.OnContext(context.Background())
+}
+
+// As On but abandoned if the context is done before it is written
+func (group *Group) OnContext(ctx context.Context) (int, []byte, error) {
+	return group.led().OnContext(ctx)
+}
+
+// Send the Off command to the group
+func (group *Group) Off() (int, []byte, error) {
+	return group.OffContext(context.Background())
+}
+
+// As Off but abandoned if the context is done before it is written
+func (group *Group) OffContext(ctx context.Context) (int, []byte, error) {
+	return group.led().OffContext(ctx)
+}
+
+// Send the Toggle command to the group
+func (group *Group) Toggle() (int, []byte, error) {
+	return group.ToggleContext(context.Background())
+}
+
+// As Toggle but abandoned if the context is done before it is written
+func (group *Group) ToggleContext(ctx context.Context) (int, []byte, error) {
+	return group.led().ToggleContext(ctx)
+}
+
+// Set the light level of the group immediately
+func (group *Group) SetLevel(level byte) (int, []byte, error) {
+	return group.SetLevelContext(context.Background(), level)
+}
+
+// As SetLevel but abandoned if the context is done before it is written
+func (group *Group) SetLevelContext(ctx context.Context, level byte) (int, []byte, error) {
+	return group.led().SetLevelContext(ctx, level)
+}
+
+// Fade down legacy
+func (group *Group) FadeDown(f Fade) (int, []byte, error) {
+	return group.FadeDownContext(context.Background(), f)
+}
+
+// As FadeDown but abandoned if the context is done before it is written
+func (group *Group) FadeDownContext(ctx context.Context, f Fade) (int, []byte, error) {
+	return group.led().FadeDownContext(ctx, f)
+}
+
+// Fade the group to a light level
+func (group *Group) Fade(f Fade) (int, []byte, error) {
+	return group.FadeContext(context.Background(), f)
+}
+
+// As Fade but abandoned if the context is done before it is written
+func (group *Group) FadeContext(ctx context.Context, f Fade) (int, []byte, error) {
+	return group.led().FadeContext(ctx, f)
+}
+
+// Set the group Red, Green and Blue levels
+func (group *Group) SetRGB(r, g, b byte) (int, []byte, error) {
+	return group.SetRGBContext(context.Background(), r, g, b)
+}
+
+// As SetRGB but abandoned if the context is done before it is written
+func (group *Group) SetRGBContext(ctx context.Context, r, g, b byte) (int, []byte, error) {
+	return group.led().SetRGBContext(ctx, r, g, b)
+}
+
+// Fade the group to a RGB level
+func (group *Group) FadeRGB(r, g, b Fade) (int, []byte, error) {
+	return group.FadeRGBContext(context.Background(), r, g, b)
+}
+
+// As FadeRGB but abandoned if the context is done before it is written
+func (group *Group) FadeRGBContext(ctx context.Context, r, g, b Fade) (int, []byte, error) {
+	return group.led().FadeRGBContext(ctx, r, g, b)
+}
+
+// Set the group colour, sent as given
+func (group *Group) SetColor(c Color) (int, []byte, error) {
+	return group.SetColorContext(context.Background(), c)
+}
+
+// As SetColor but abandoned if the context is done before it is written
+func (group *Group) SetColorContext(ctx context.Context, c Color) (int, []byte, error) {
+	return group.led().SetColorContext(ctx, c)
+}
+
+// Fade the group to a colour with the same timing on every channel
+func (group *Group) FadeColor(c Color, t Timing) (int, []byte, error) {
+	return group.FadeColorContext(context.Background(), c, t)
+}
+
+// As FadeColor but abandoned if the context is done before it is written
+func (group *Group) FadeColorContext(ctx context.Context, c Color, t Timing) (int, []byte, error) {
+	return group.led().FadeColorContext(ctx, c, t)
+}
+
+// Constructs a new Group for the given psuedo address
+func NewGroup(addr uint16, writer io.Writer) *Group {
+	return &Group{
+		Addr:   addr,
+		Writer: writer,
+	}
+}
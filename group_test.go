@@ -0,0 +1,168 @@
+package lightswarm
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup(t *testing.T) {
+	tt := []struct {
+		name     string
+		addr     uint16
+		buff     *bytes.Buffer
+		cmd      func(*Group) (int, []byte, error)
+		expected []byte
+		n        int
+		err      error
+	}{
+		{
+			"turn 4096 on",
+			4096,
+			bytes.NewBuffer(nil),
+			(*Group).On,
+			[]byte{END, 16, 0, ON, 48, END},
+			6,
+			nil,
+		},
+		{
+			"turn 4096 off",
+			4096,
+			bytes.NewBuffer(nil),
+			(*Group).Off,
+			[]byte{END, 16, 0, OFF, 49, END},
+			6,
+			nil,
+		},
+		{
+			"toggle 4096",
+			4096,
+			bytes.NewBuffer(nil),
+			(*Group).Toggle,
+			[]byte{END, 16, 0, TOGGLE, 61, END},
+			6,
+			nil,
+		},
+		{
+			"set 4096 level to 64",
+			4096,
+			bytes.NewBuffer(nil),
+			func(g *Group) (int, []byte, error) { return g.SetLevel(64) },
+			[]byte{END, 16, 0, SET_LEVEL, 64, 114, END},
+			7,
+			nil,
+		},
+		{
+			"fade 4096 down",
+			4096,
+			bytes.NewBuffer(nil),
+			func(g *Group) (int, []byte, error) { return g.FadeDown(Fade{0, 1, 1}) },
+			[]byte{END, 16, 0, FADE_DOWN, 0, 1, 1, 52, END},
+			9,
+			nil,
+		},
+		{
+			"fade 4096 to 255 at 1 interval with 1 step",
+			4096,
+			bytes.NewBuffer(nil),
+			func(g *Group) (int, []byte, error) { return g.Fade(Fade{255, 1, 1}) },
+			[]byte{END, 16, 0, FADE_TO_LEVEL, 255, 1, 1, 204, END},
+			9,
+			nil,
+		},
+		{
+			"set 4096 RGB to 1, 2, 3",
+			4096,
+			bytes.NewBuffer(nil),
+			func(g *Group) (int, []byte, error) { return g.SetRGB(1, 2, 3) },
+			[]byte{END, 16, 0, SET_RGB_LEVELS, 1, 2, 3, 60, END},
+			9,
+			nil,
+		},
+		{
+			"fade 4096 RGB to 1, 2, 3",
+			4096,
+			bytes.NewBuffer(nil),
+			func(g *Group) (int, []byte, error) {
+				return g.FadeRGB(Fade{1, 1, 1}, Fade{2, 1, 1}, Fade{3, 1, 1})
+			},
+			[]byte{END, 16, 0, FADE_RGB_TO_LEVEL, 1, 1, 1, 2, 1, 1, 3, 1, 1, 33, END},
+			15,
+			nil,
+		},
+		{
+			"set 4096 colour to 1, 2, 3",
+			4096,
+			bytes.NewBuffer(nil),
+			func(g *Group) (int, []byte, error) { return g.SetColor(Color{1, 2, 3}) },
+			[]byte{END, 16, 0, SET_RGB_LEVELS, 1, 2, 3, 60, END},
+			9,
+			nil,
+		},
+		{
+			"fade 4096 colour to 1, 2, 3",
+			4096,
+			bytes.NewBuffer(nil),
+			func(g *Group) (int, []byte, error) {
+				return g.FadeColor(Color{1, 2, 3}, Timing{Interval: 1, Step: 1})
+			},
+			[]byte{END, 16, 0, FADE_RGB_TO_LEVEL, 1, 1, 1, 2, 1, 1, 3, 1, 1, 33, END},
+			15,
+			nil,
+		},
+		{
+			"send 4096 set level",
+			4096,
+			bytes.NewBuffer(nil),
+			func(g *Group) (int, []byte, error) { return g.Send(&SetLevel{Level: 64}) },
+			[]byte{END, 16, 0, SET_LEVEL, 64, 114, END},
+			7,
+			nil,
+		},
+		{
+			"send 4096 invalid fade",
+			4096,
+			bytes.NewBuffer(nil),
+			func(g *Group) (int, []byte, error) { return g.Send(&FadeToLevel{Fade{256, 1, 1}}) },
+			[]byte{},
+			0,
+			ErrArgRange,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			group := &Group{tc.addr, tc.buff}
+			n, b, err := tc.cmd(group)
+			t.Logf("%s bytes: %v", tc.name, b)
+			assert.Equal(t, tc.n, n)
+			assert.Equal(t, tc.err, err)
+			bs, err := ioutil.ReadAll(tc.buff)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, bs)
+		})
+	}
+}
+
+func TestNewGroup(t *testing.T) {
+	tt := []struct {
+		name    string
+		address uint16
+		writer  io.Writer
+	}{
+		{
+			"new 4096 with ioutil.Discard writer",
+			4096,
+			ioutil.Discard,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			group := NewGroup(tc.address, tc.writer)
+			assert.Equal(t, tc.address, group.Addr)
+			assert.Equal(t, tc.writer, group.Writer)
+		})
+	}
+}
@@ -0,0 +1,258 @@
+/*
+Package httpapi exposes LightSwarm LED's and groups as HTTP/JSON resources.
+
+	PUT  /leds/690          {"on": true}  or  {"level": 128}
+	POST /leds/690/toggle
+	POST /leds/690/fade     {"level": 255, "interval": 1, "step": 5}
+	PUT  /leds/690/rgb      {"r": 85, "g": 199, "b": 237}
+	POST /leds/690/fade-rgb {"r": {"level": 85, ...}, "g": {...}, "b": {...}}
+	GET  /leds/690          tracked state, requires a Tracker
+
+Groups are addressed by psuedo address under /groups/ with the same
+sub-resources. Successful commands respond with the encoded frame:
+
+	{"n": 6, "bytes": "c002b22090c0"}
+*/
+package httpapi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/thisissoon/lightswarm"
+)
+
+// Request errors
+var (
+	errNotFound         = errors.New("not found")
+	errMethodNotAllowed = errors.New("method not allowed")
+	errNoTracker        = errors.New("state tracking not enabled")
+)
+
+// Request body for PUT /leds/{addr}, unset fields are ignored
+type Power struct {
+	On    *bool `json:"on,omitempty"`
+	Level *byte `json:"level,omitempty"`
+}
+
+// Request body for fades
+type Fade struct {
+	Level    int `json:"level"`
+	Interval int `json:"interval"`
+	Step     int `json:"step"`
+}
+
+// Returns the lightswarm Fade
+func (f Fade) fade() lightswarm.Fade {
+	return lightswarm.Fade{
+		Level:    f.Level,
+		Interval: f.Interval,
+		Step:     f.Step,
+	}
+}
+
+// Request body for PUT /leds/{addr}/rgb
+type RGB struct {
+	R byte `json:"r"`
+	G byte `json:"g"`
+	B byte `json:"b"`
+}
+
+// Request body for POST /leds/{addr}/fade-rgb
+type FadeRGB struct {
+	R Fade `json:"r"`
+	G Fade `json:"g"`
+	B Fade `json:"b"`
+}
+
+// Returns the Red, Green and Blue lightswarm Fades
+func (f FadeRGB) fades() (r, g, b lightswarm.Fade) {
+	return f.R.fade(), f.G.fade(), f.B.fade()
+}
+
+// Response body for commands
+type Response struct {
+	N     int    `json:"n"`
+	Bytes string `json:"bytes"`
+}
+
+// Response body for GET /leds/{addr}
+type State struct {
+	On    bool    `json:"on"`
+	Level byte    `json:"level"`
+	RGB   [3]byte `json:"rgb"`
+}
+
+// Response body for errors
+type Error struct {
+	Error string `json:"error"`
+}
+
+// Serves the API, commands are written to Writer which should usually be
+// a lightswarm.Bus so concurrent requests are not interleaved
+type Server struct {
+	// Exported Fields
+	Writer  io.Writer
+	Tracker *lightswarm.Tracker // Optional, enables GET requests
+}
+
+// Writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Writes a JSON error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, Error{err.Error()})
+}
+
+// Decodes a JSON request body
+func decode(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return errors.New("invalid request body: " + err.Error())
+	}
+	return nil
+}
+
+// Routes the request to the addressed LED or group
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+	addr, err := strconv.ParseUint(parts[1], 10, 16)
+	if err != nil {
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+	var l lightswarm.ContextLight
+	switch parts[0] {
+	case "leds":
+		l = lightswarm.New(uint16(addr), s.Writer)
+	case "groups":
+		l = lightswarm.NewGroup(uint16(addr), s.Writer)
+	default:
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+	resource := ""
+	if len(parts) == 3 {
+		resource = parts[2]
+	}
+	if resource == "" && r.Method == http.MethodGet {
+		s.state(w, uint16(addr))
+		return
+	}
+	s.command(w, r, l, resource)
+}
+
+// Responds with the tracked state of the address
+func (s *Server) state(w http.ResponseWriter, addr uint16) {
+	if s.Tracker == nil {
+		writeError(w, http.StatusNotImplemented, errNoTracker)
+		return
+	}
+	state, ok := s.Tracker.State(addr)
+	if !ok {
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, State{
+		On:    state.On,
+		Level: state.Level,
+		RGB:   state.RGB,
+	})
+}
+
+// Sends the command for the resource and method to the light
+func (s *Server) command(w http.ResponseWriter, r *http.Request, l lightswarm.ContextLight, resource string) {
+	ctx := r.Context()
+	var (
+		n   int
+		b   []byte
+		err error
+	)
+	switch {
+	case resource == "" && r.Method == http.MethodPut:
+		var body Power
+		if err := decode(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if body.On == nil && body.Level == nil {
+			writeError(w, http.StatusBadRequest, errors.New("on or level is required"))
+			return
+		}
+		if body.Level != nil {
+			n, b, err = l.SetLevelContext(ctx, *body.Level)
+		}
+		if body.On != nil && err == nil {
+			var m int
+			var bs []byte
+			if *body.On {
+				m, bs, err = l.OnContext(ctx)
+			} else {
+				m, bs, err = l.OffContext(ctx)
+			}
+			n, b = n+m, append(b, bs...)
+		}
+	case resource == "toggle" && r.Method == http.MethodPost:
+		n, b, err = l.ToggleContext(ctx)
+	case resource == "fade" && r.Method == http.MethodPost:
+		var body Fade
+		if err := decode(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		n, b, err = l.FadeContext(ctx, body.fade())
+	case resource == "rgb" && r.Method == http.MethodPut:
+		var body RGB
+		if err := decode(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		n, b, err = l.SetRGBContext(ctx, body.R, body.G, body.B)
+	case resource == "fade-rgb" && r.Method == http.MethodPost:
+		var body FadeRGB
+		if err := decode(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		red, green, blue := body.fades()
+		n, b, err = l.FadeRGBContext(ctx, red, green, blue)
+	case resource == "" || resource == "toggle" || resource == "fade" || resource == "rgb" || resource == "fade-rgb":
+		writeError(w, http.StatusMethodNotAllowed, errMethodNotAllowed)
+		return
+	default:
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+	// arguments are validated before anything is written
+	if err == lightswarm.ErrArgRange || err == lightswarm.ErrArgs {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{
+		N:     n,
+		Bytes: hex.EncodeToString(b),
+	})
+}
+
+// Constructs a new Server writing to the given writer
+func New(writer io.Writer) *Server {
+	return &Server{
+		Writer: writer,
+	}
+}
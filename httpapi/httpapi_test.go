@@ -0,0 +1,267 @@
+package httpapi
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thisissoon/lightswarm"
+)
+
+// A writer that always fails
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("serial port wedged")
+}
+
+func TestServerCommands(t *testing.T) {
+	tt := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		status   int
+		response string
+		written  []byte
+	}{
+		{
+			"turn 690 on",
+			http.MethodPut,
+			"/leds/690",
+			`{"on": true}`,
+			http.StatusOK,
+			`{"n":6,"bytes":"c002b22090c0"}`,
+			[]byte{0xC0, 2, 178, lightswarm.ON, 144, 0xC0},
+		},
+		{
+			"turn 690 off",
+			http.MethodPut,
+			"/leds/690",
+			`{"on": false}`,
+			http.StatusOK,
+			`{"n":6,"bytes":"c002b22191c0"}`,
+			[]byte{0xC0, 2, 178, lightswarm.OFF, 145, 0xC0},
+		},
+		{
+			"set 690 level and turn on",
+			http.MethodPut,
+			"/leds/690",
+			`{"on": true, "level": 128}`,
+			http.StatusOK,
+			`{"n":13,"bytes":"c002b2228012c0c002b22090c0"}`,
+			[]byte{0xC0, 2, 178, lightswarm.SET_LEVEL, 128, 18, 0xC0, 0xC0, 2, 178, lightswarm.ON, 144, 0xC0},
+		},
+		{
+			"toggle 690",
+			http.MethodPost,
+			"/leds/690/toggle",
+			"",
+			http.StatusOK,
+			`{"n":6,"bytes":"c002b22d9dc0"}`,
+			[]byte{0xC0, 2, 178, lightswarm.TOGGLE, 157, 0xC0},
+		},
+		{
+			"fade 690",
+			http.MethodPost,
+			"/leds/690/fade",
+			`{"level": 255, "interval": 1, "step": 1}`,
+			http.StatusOK,
+			`{"n":9,"bytes":"c002b223ff01016cc0"}`,
+			[]byte{0xC0, 2, 178, lightswarm.FADE_TO_LEVEL, 255, 1, 1, 108, 0xC0},
+		},
+		{
+			"set 690 rgb",
+			http.MethodPut,
+			"/leds/690/rgb",
+			`{"r": 85, "g": 199, "b": 237}`,
+			http.StatusOK,
+			`{"n":9,"bytes":"c002b22c55c7ede3c0"}`,
+			[]byte{0xC0, 2, 178, lightswarm.SET_RGB_LEVELS, 85, 199, 237, 227, 0xC0},
+		},
+		{
+			"fade 690 rgb",
+			http.MethodPost,
+			"/leds/690/fade-rgb",
+			`{"r": {"level": 85, "interval": 1, "step": 1}, "g": {"level": 199, "interval": 1, "step": 1}, "b": {"level": 237, "interval": 1, "step": 1}}`,
+			http.StatusOK,
+			`{"n":15,"bytes":"c002b231550101c70101ed0101fec0"}`,
+			[]byte{0xC0, 2, 178, lightswarm.FADE_RGB_TO_LEVEL, 85, 1, 1, 199, 1, 1, 237, 1, 1, 254, 0xC0},
+		},
+		{
+			"turn group 4096 on",
+			http.MethodPut,
+			"/groups/4096",
+			`{"on": true}`,
+			http.StatusOK,
+			`{"n":6,"bytes":"c010002030c0"}`,
+			[]byte{0xC0, 16, 0, lightswarm.ON, 48, 0xC0},
+		},
+		{
+			"empty power body",
+			http.MethodPut,
+			"/leds/690",
+			`{}`,
+			http.StatusBadRequest,
+			`{"error":"on or level is required"}`,
+			nil,
+		},
+		{
+			"invalid json",
+			http.MethodPost,
+			"/leds/690/fade",
+			`{`,
+			http.StatusBadRequest,
+			`{"error":"invalid request body: unexpected EOF"}`,
+			nil,
+		},
+		{
+			"rgb out of range",
+			http.MethodPut,
+			"/leds/690/rgb",
+			`{"r": 256}`,
+			http.StatusBadRequest,
+			`{"error":"invalid request body: json: cannot unmarshal number 256 into Go struct field RGB.r of type uint8"}`,
+			nil,
+		},
+		{
+			"fade level out of range",
+			http.MethodPost,
+			"/leds/690/fade",
+			`{"level": -1}`,
+			http.StatusBadRequest,
+			`{"error":"lightswarm: command argument out of range"}`,
+			nil,
+		},
+		{
+			"fade interval out of range",
+			http.MethodPost,
+			"/leds/690/fade",
+			`{"level": 255, "interval": 256}`,
+			http.StatusBadRequest,
+			`{"error":"lightswarm: command argument out of range"}`,
+			nil,
+		},
+		{
+			"fade rgb step out of range",
+			http.MethodPost,
+			"/leds/690/fade-rgb",
+			`{"r": {"level": 255}, "g": {"level": 255}, "b": {"level": 255, "step": 128}}`,
+			http.StatusBadRequest,
+			`{"error":"lightswarm: command argument out of range"}`,
+			nil,
+		},
+		{
+			"wrong method",
+			http.MethodGet,
+			"/leds/690/fade",
+			"",
+			http.StatusMethodNotAllowed,
+			`{"error":"method not allowed"}`,
+			nil,
+		},
+		{
+			"unknown resource",
+			http.MethodPost,
+			"/leds/690/explode",
+			"",
+			http.StatusNotFound,
+			`{"error":"not found"}`,
+			nil,
+		},
+		{
+			"invalid address",
+			http.MethodPut,
+			"/leds/65536",
+			`{"on": true}`,
+			http.StatusNotFound,
+			`{"error":"not found"}`,
+			nil,
+		},
+		{
+			"unknown collection",
+			http.MethodPut,
+			"/lamps/690",
+			`{"on": true}`,
+			http.StatusNotFound,
+			`{"error":"not found"}`,
+			nil,
+		},
+		{
+			"get without tracker",
+			http.MethodGet,
+			"/leds/690",
+			"",
+			http.StatusNotImplemented,
+			`{"error":"state tracking not enabled"}`,
+			nil,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			buff := bytes.NewBuffer(nil)
+			s := New(buff)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			s.ServeHTTP(w, r)
+			assert.Equal(t, tc.status, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			assert.JSONEq(t, tc.response, w.Body.String())
+			assert.Equal(t, tc.written, buff.Bytes())
+		})
+	}
+}
+
+func TestServerWriteError(t *testing.T) {
+	s := New(errWriter{})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/leds/690", strings.NewReader(`{"on": true}`))
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.JSONEq(t, `{"error":"serial port wedged"}`, w.Body.String())
+}
+
+func TestServerState(t *testing.T) {
+	tracker := lightswarm.NewTracker(nil)
+	s := New(tracker)
+	s.Tracker = tracker
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodPut, "/leds/690", `{"on": true}`},
+		{http.MethodPut, "/leds/690/rgb", `{"r": 1, "g": 2, "b": 3}`},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(req.method, req.path, strings.NewReader(req.body)))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	tt := []struct {
+		name     string
+		path     string
+		status   int
+		response string
+	}{
+		{
+			"tracked led",
+			"/leds/690",
+			http.StatusOK,
+			`{"on":true,"level":0,"rgb":[1,2,3]}`,
+		},
+		{
+			"untracked led",
+			"/leds/227",
+			http.StatusNotFound,
+			`{"error":"not found"}`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, tc.status, w.Code)
+			assert.JSONEq(t, tc.response, w.Body.String())
+		})
+	}
+}
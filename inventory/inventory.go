@@ -0,0 +1,222 @@
+/*
+Package inventory loads a fixture inventory describing every LightSwarm
+address in a venue and builds a Registry of named LED and Group handles
+from it.
+
+	{
+	  "profiles": {
+	    "warm": {"gamma": [2.2, 2.2, 2.2], "white": "#ffd0a0"}
+	  },
+	  "groups": [
+	    {"addr": 4096, "name": "bar"}
+	  ],
+	  "fixtures": [
+	    {"addr": 690, "name": "bar-1", "zone": "bar", "type": "rgb", "profile": "warm", "groups": [4096]},
+	    {"addr": 227, "name": "door", "zone": "entrance", "type": "mono"}
+	  ]
+	}
+
+The same inventory can be written as YAML or TOML, Load picks the decoder
+from the file extension: .json, .yaml, .yml or .toml.
+
+	cfg, err := inventory.Load("venue.yaml")
+*/
+package inventory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/thisissoon/lightswarm"
+	"gopkg.in/yaml.v2"
+)
+
+// Returned when loading a file with an unregistered extension
+var ErrUnknownFormat = errors.New("inventory: unknown file format")
+
+// The channels a fixture has
+type Type string
+
+// Fixture types
+const (
+	Mono Type = "mono" // Single channel, driven with levels
+	RGB  Type = "rgb"  // Red, Green and Blue channels
+)
+
+// A colour correction profile shared by fixtures
+type Profile struct {
+	Gamma [3]float64        `json:"gamma" yaml:"gamma" toml:"gamma"` // Red, Green and Blue gamma, 0 is linear
+	White *lightswarm.Color `json:"white,omitempty" yaml:"white,omitempty" toml:"white,omitempty"`
+}
+
+// Returns the calibration for the profile
+func (p Profile) calibration() *lightswarm.Calibration {
+	white := lightswarm.Color{R: 255, G: 255, B: 255}
+	if p.White != nil {
+		white = *p.White
+	}
+	return lightswarm.NewCalibration(p.Gamma, white)
+}
+
+// A named psuedo address
+type Group struct {
+	Addr int    `json:"addr" yaml:"addr" toml:"addr"`
+	Name string `json:"name" yaml:"name" toml:"name"`
+}
+
+// A single fixture. Addresses are ints rather than uint16 so out of range
+// values are reported by Validate instead of failing to decode.
+type Fixture struct {
+	Addr    int    `json:"addr" yaml:"addr" toml:"addr"`
+	Name    string `json:"name,omitempty" yaml:"name,omitempty" toml:"name,omitempty"`
+	Zone    string `json:"zone,omitempty" yaml:"zone,omitempty" toml:"zone,omitempty"`
+	Type    Type   `json:"type,omitempty" yaml:"type,omitempty" toml:"type,omitempty"` // Defaults to Mono
+	Profile string `json:"profile,omitempty" yaml:"profile,omitempty" toml:"profile,omitempty"`
+	Groups  []int  `json:"groups,omitempty" yaml:"groups,omitempty" toml:"groups,omitempty"` // Psuedo addresses
+}
+
+// A fixture inventory
+type Config struct {
+	Profiles map[string]Profile `json:"profiles,omitempty" yaml:"profiles,omitempty" toml:"profiles,omitempty"`
+	Groups   []Group            `json:"groups,omitempty" yaml:"groups,omitempty" toml:"groups,omitempty"`
+	Fixtures []Fixture          `json:"fixtures" yaml:"fixtures" toml:"fixtures"`
+}
+
+// Every problem found validating a Config
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "inventory: " + strings.Join(e.Problems, "; ")
+}
+
+// Reports whether the address fits in the two address bytes of a frame
+func validAddr(addr int) bool {
+	return addr >= 0 && addr <= math.MaxUint16
+}
+
+// Checks every address is in range and unique, names are unique and
+// types, profiles and groups exist, reporting every problem at once
+func (cfg Config) Validate() error {
+	problems := []string{}
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	addrs := map[int]string{}
+	names := map[string]bool{}
+	name := func(kind string, addr int, n string) {
+		if n == "" {
+			return
+		}
+		if names[n] {
+			addf("%s %d: duplicate name %q", kind, addr, n)
+		}
+		names[n] = true
+	}
+	groups := map[int]bool{}
+	for _, g := range cfg.Groups {
+		switch {
+		case !validAddr(g.Addr):
+			addf("group %d: address out of range", g.Addr)
+		case addrs[g.Addr] != "":
+			addf("group %d: duplicate address", g.Addr)
+		default:
+			addrs[g.Addr] = "group"
+		}
+		groups[g.Addr] = true
+		name("group", g.Addr, g.Name)
+	}
+	for _, f := range cfg.Fixtures {
+		switch {
+		case !validAddr(f.Addr):
+			addf("fixture %d: address out of range", f.Addr)
+		case addrs[f.Addr] != "":
+			addf("fixture %d: duplicate address, already used by a %s", f.Addr, addrs[f.Addr])
+		default:
+			addrs[f.Addr] = "fixture"
+		}
+		name("fixture", f.Addr, f.Name)
+		if f.Type != "" && f.Type != Mono && f.Type != RGB {
+			addf("fixture %d: unknown type %q", f.Addr, f.Type)
+		}
+		if _, ok := cfg.Profiles[f.Profile]; f.Profile != "" && !ok {
+			addf("fixture %d: unknown profile %q", f.Addr, f.Profile)
+		}
+		for _, g := range f.Groups {
+			if !validAddr(g) {
+				addf("fixture %d: group %d out of range", f.Addr, g)
+			}
+		}
+	}
+	// groups only referenced by fixtures must not clash with fixtures
+	for _, f := range cfg.Fixtures {
+		for _, g := range f.Groups {
+			if !groups[g] && addrs[g] == "fixture" {
+				addf("fixture %d: group %d is a fixture address", f.Addr, g)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{problems}
+	}
+	return nil
+}
+
+// Decodes data into v
+type UnmarshalFunc func(data []byte, v interface{}) error
+
+// Registered decoders by file extension
+var (
+	formatsMu sync.RWMutex
+	formats   = map[string]UnmarshalFunc{
+		".json": json.Unmarshal,
+		".yaml": yaml.Unmarshal,
+		".yml":  yaml.Unmarshal,
+		".toml": toml.Unmarshal,
+	}
+)
+
+// Registers the decoder used by Load for files with the extension,
+// replacing any existing decoder. JSON, YAML and TOML are registered by
+// default.
+func RegisterFormat(ext string, unmarshal UnmarshalFunc) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats[strings.ToLower(ext)] = unmarshal
+}
+
+// Decodes and validates a Config
+func Parse(data []byte, unmarshal UnmarshalFunc) (Config, error) {
+	var cfg Config
+	if err := unmarshal(data, &cfg); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Reads and validates a Config from a file, decoded according to its
+// extension
+func Load(path string) (Config, error) {
+	formatsMu.RLock()
+	unmarshal, ok := formats[strings.ToLower(filepath.Ext(path))]
+	formatsMu.RUnlock()
+	if !ok {
+		return Config{}, ErrUnknownFormat
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	return Parse(data, unmarshal)
+}
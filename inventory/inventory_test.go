@@ -0,0 +1,223 @@
+package inventory
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thisissoon/lightswarm"
+)
+
+const venue = `{
+  "profiles": {
+    "warm": {"gamma": [2.2, 2.2, 2.2], "white": "#ffd0a0"}
+  },
+  "groups": [
+    {"addr": 4096, "name": "bar"}
+  ],
+  "fixtures": [
+    {"addr": 690, "name": "bar-1", "zone": "bar", "type": "rgb", "profile": "warm", "groups": [4096, 4097]},
+    {"addr": 691, "name": "bar-2", "zone": "bar", "type": "rgb", "groups": [4096]},
+    {"addr": 227, "name": "door", "zone": "entrance"}
+  ]
+}`
+
+// The venue as YAML
+const venueYAML = `
+profiles:
+  warm:
+    gamma: [2.2, 2.2, 2.2]
+    white: "#ffd0a0"
+groups:
+  - addr: 4096
+    name: bar
+fixtures:
+  - {addr: 690, name: bar-1, zone: bar, type: rgb, profile: warm, groups: [4096, 4097]}
+  - {addr: 691, name: bar-2, zone: bar, type: rgb, groups: [4096]}
+  - {addr: 227, name: door, zone: entrance}
+`
+
+// The venue as TOML
+const venueTOML = `
+[profiles.warm]
+gamma = [2.2, 2.2, 2.2]
+white = "#ffd0a0"
+
+[[groups]]
+addr = 4096
+name = "bar"
+
+[[fixtures]]
+addr = 690
+name = "bar-1"
+zone = "bar"
+type = "rgb"
+profile = "warm"
+groups = [4096, 4097]
+
+[[fixtures]]
+addr = 691
+name = "bar-2"
+zone = "bar"
+type = "rgb"
+groups = [4096]
+
+[[fixtures]]
+addr = 227
+name = "door"
+zone = "entrance"
+`
+
+func TestConfigValidate(t *testing.T) {
+	tt := []struct {
+		name     string
+		config   Config
+		problems []string
+	}{
+		{
+			"valid",
+			Config{
+				Groups:   []Group{{Addr: 4096, Name: "all"}},
+				Fixtures: []Fixture{{Addr: 1, Name: "a", Groups: []int{4096, 4097}}, {Addr: 2, Type: RGB}, {Addr: 65535}},
+			},
+			nil,
+		},
+		{
+			"out of range",
+			Config{
+				Groups:   []Group{{Addr: 70000}},
+				Fixtures: []Fixture{{Addr: -1}, {Addr: 65536}, {Addr: 1, Groups: []int{65536}}},
+			},
+			[]string{
+				"group 70000: address out of range",
+				"fixture -1: address out of range",
+				"fixture 65536: address out of range",
+				"fixture 1: group 65536 out of range",
+			},
+		},
+		{
+			"duplicates",
+			Config{
+				Groups:   []Group{{Addr: 4096, Name: "bar"}, {Addr: 4096}},
+				Fixtures: []Fixture{{Addr: 1, Name: "bar"}, {Addr: 1}, {Addr: 4096}},
+			},
+			[]string{
+				"group 4096: duplicate address",
+				"fixture 1: duplicate name \"bar\"",
+				"fixture 1: duplicate address, already used by a fixture",
+				"fixture 4096: duplicate address, already used by a group",
+			},
+		},
+		{
+			"unknown type and profile",
+			Config{
+				Fixtures: []Fixture{{Addr: 1, Type: "rgbw", Profile: "cool"}},
+			},
+			[]string{
+				"fixture 1: unknown type \"rgbw\"",
+				"fixture 1: unknown profile \"cool\"",
+			},
+		},
+		{
+			"group is a fixture",
+			Config{
+				Fixtures: []Fixture{{Addr: 1, Groups: []int{2}}, {Addr: 2}},
+			},
+			[]string{
+				"fixture 1: group 2 is a fixture address",
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if tc.problems == nil {
+				assert.Nil(t, err)
+				return
+			}
+			assert.Equal(t, &ValidationError{tc.problems}, err)
+		})
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	err := &ValidationError{[]string{"a", "b"}}
+	assert.Equal(t, "inventory: a; b", err.Error())
+}
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse([]byte(venue), json.Unmarshal)
+	assert.Nil(t, err)
+	assert.Len(t, cfg.Fixtures, 3)
+	assert.Equal(t, Fixture{
+		Addr:    690,
+		Name:    "bar-1",
+		Zone:    "bar",
+		Type:    RGB,
+		Profile: "warm",
+		Groups:  []int{4096, 4097},
+	}, cfg.Fixtures[0])
+	assert.Equal(t, Profile{
+		Gamma: [3]float64{2.2, 2.2, 2.2},
+		White: &lightswarm.Color{R: 255, G: 208, B: 160},
+	}, cfg.Profiles["warm"])
+	_, err = Parse([]byte(`{"fixtures": [{"addr": 1}, {"addr": 1}]}`), json.Unmarshal)
+	assert.IsType(t, &ValidationError{}, err)
+	_, err = Parse([]byte(`{`), json.Unmarshal)
+	assert.NotNil(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inventory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	tt := []struct {
+		name string
+		data string
+	}{
+		{"venue.JSON", venue},
+		{"venue.yaml", venueYAML},
+		{"venue.yml", venueYAML},
+		{"venue.toml", venueTOML},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := Load(write(tc.name, tc.data))
+			assert.Nil(t, err)
+			expected, _ := Parse([]byte(venue), json.Unmarshal)
+			assert.Equal(t, expected, cfg)
+		})
+	}
+	// unregistered format
+	path := write("venue.fake", "fixtures: 1")
+	_, err = Load(path)
+	assert.Equal(t, ErrUnknownFormat, err)
+	// registered format
+	RegisterFormat(".fake", func(data []byte, v interface{}) error {
+		v.(*Config).Fixtures = []Fixture{{Addr: 1}}
+		return nil
+	})
+	defer func() {
+		formatsMu.Lock()
+		delete(formats, ".fake")
+		formatsMu.Unlock()
+	}()
+	cfg, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []Fixture{{Addr: 1}}, cfg.Fixtures)
+	// missing file
+	_, err = Load(filepath.Join(dir, "missing.json"))
+	assert.True(t, os.IsNotExist(err))
+}
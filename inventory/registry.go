@@ -0,0 +1,169 @@
+package inventory
+
+import (
+	"context"
+	"io"
+	"sort"
+
+	"github.com/thisissoon/lightswarm"
+)
+
+// Named LED and Group handles for the fixtures in a Config
+type Registry struct {
+	// Exported Fields
+	Writer io.Writer
+
+	fixtures map[uint16]Fixture
+	names    map[string]uint16
+	groups   map[string]uint16
+	members  map[uint16][]uint16 // psuedo address -> fixture addresses
+	profiles map[string]*lightswarm.Calibration
+}
+
+// Returns the LED for the fixture at the address
+func (r *Registry) led(f Fixture) *lightswarm.LED {
+	return lightswarm.New(uint16(f.Addr), r.Writer)
+}
+
+// Returns the LED for the named fixture
+func (r *Registry) LED(name string) (*lightswarm.LED, bool) {
+	addr, ok := r.names[name]
+	if !ok {
+		return nil, false
+	}
+	return r.led(r.fixtures[addr]), true
+}
+
+// Returns the LED for the fixture at the address
+func (r *Registry) Addr(addr uint16) (*lightswarm.LED, bool) {
+	f, ok := r.fixtures[addr]
+	if !ok {
+		return nil, false
+	}
+	return r.led(f), true
+}
+
+// Returns the named group
+func (r *Registry) Group(name string) (*lightswarm.Group, bool) {
+	addr, ok := r.groups[name]
+	if !ok {
+		return nil, false
+	}
+	return lightswarm.NewGroup(addr, r.Writer), true
+}
+
+// Returns the calibration of the profile of the fixture at the address,
+// nil if it has none
+func (r *Registry) Calibration(addr uint16) *lightswarm.Calibration {
+	return r.profiles[r.fixtures[addr].Profile]
+}
+
+// Returns the named fixture wrapped to correct colours with its profile,
+// colours are sent unchanged to fixtures without a profile
+func (r *Registry) Calibrated(name string) (lightswarm.Calibrated, bool) {
+	led, ok := r.LED(name)
+	if !ok {
+		return lightswarm.Calibrated{}, false
+	}
+	return lightswarm.Calibrated{ContextLight: led, Cal: r.Calibration(led.Addr)}, true
+}
+
+// Returns the fixture with the address
+func (r *Registry) Fixture(addr uint16) (Fixture, bool) {
+	f, ok := r.fixtures[addr]
+	return f, ok
+}
+
+// Returns every fixture in ascending address order
+func (r *Registry) Fixtures() []Fixture {
+	fixtures := make([]Fixture, 0, len(r.fixtures))
+	for _, f := range r.fixtures {
+		fixtures = append(fixtures, f)
+	}
+	sort.Slice(fixtures, func(i, j int) bool { return fixtures[i].Addr < fixtures[j].Addr })
+	return fixtures
+}
+
+// Returns the LED's in the zone in ascending address order
+func (r *Registry) Zone(zone string) []*lightswarm.LED {
+	leds := []*lightswarm.LED{}
+	for _, f := range r.Fixtures() {
+		if f.Zone == zone {
+			leds = append(leds, r.led(f))
+		}
+	}
+	return leds
+}
+
+// Returns the fixture addresses in the psuedo address in ascending order
+func (r *Registry) Members(pseudo uint16) []uint16 {
+	return append([]uint16{}, r.members[pseudo]...)
+}
+
+// Writes the psuedo address table of every fixture, erasing each table
+// before adding the fixture's groups so the hardware matches the
+// inventory
+func (r *Registry) PushPseudoTables() (int, error) {
+	return r.PushPseudoTablesContext(context.Background())
+}
+
+// As PushPseudoTables but abandoned if the context is done before
+// every table is written
+func (r *Registry) PushPseudoTablesContext(ctx context.Context) (int, error) {
+	total := 0
+	for _, f := range r.Fixtures() {
+		led := r.led(f)
+		n, _, err := led.ErasePseudoAddressTableContext(ctx)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		for _, g := range f.Groups {
+			n, _, err := led.SetPseudoAddressContext(ctx, uint16(g))
+			total += n
+			if err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, nil
+}
+
+// Constructs a Registry for the Config, which is validated first
+func NewRegistry(cfg Config, writer io.Writer) (*Registry, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	r := &Registry{
+		Writer:   writer,
+		fixtures: map[uint16]Fixture{},
+		names:    map[string]uint16{},
+		groups:   map[string]uint16{},
+		members:  map[uint16][]uint16{},
+		profiles: map[string]*lightswarm.Calibration{},
+	}
+	for name, p := range cfg.Profiles {
+		r.profiles[name] = p.calibration()
+	}
+	for _, g := range cfg.Groups {
+		if g.Name != "" {
+			r.groups[g.Name] = uint16(g.Addr)
+		}
+	}
+	for _, f := range cfg.Fixtures {
+		if f.Type == "" {
+			f.Type = Mono
+		}
+		r.fixtures[uint16(f.Addr)] = f
+		if f.Name != "" {
+			r.names[f.Name] = uint16(f.Addr)
+		}
+		for _, g := range f.Groups {
+			r.members[uint16(g)] = append(r.members[uint16(g)], uint16(f.Addr))
+		}
+	}
+	for _, addrs := range r.members {
+		sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	}
+	return r, nil
+}
@@ -0,0 +1,123 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thisissoon/lightswarm"
+)
+
+// A writer that always fails
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("boom")
+}
+
+// Constructs a registry for the venue
+func venueRegistry(t *testing.T, buff *bytes.Buffer) *Registry {
+	cfg, err := Parse([]byte(venue), json.Unmarshal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRegistry(cfg, buff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRegistryLookup(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	r := venueRegistry(t, buff)
+	led, ok := r.LED("bar-1")
+	assert.True(t, ok)
+	assert.Equal(t, uint16(690), led.Addr)
+	assert.Equal(t, buff, led.Writer)
+	led, ok = r.Addr(227)
+	assert.True(t, ok)
+	assert.Equal(t, uint16(227), led.Addr)
+	_, ok = r.LED("missing")
+	assert.False(t, ok)
+	_, ok = r.Addr(1)
+	assert.False(t, ok)
+	group, ok := r.Group("bar")
+	assert.True(t, ok)
+	assert.Equal(t, uint16(4096), group.Addr)
+	_, ok = r.Group("missing")
+	assert.False(t, ok)
+	f, ok := r.Fixture(227)
+	assert.True(t, ok)
+	assert.Equal(t, Mono, f.Type)
+}
+
+func TestRegistryCalibration(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	r := venueRegistry(t, buff)
+	assert.Nil(t, r.Calibration(227))
+	assert.Nil(t, r.Calibration(1))
+	led, ok := r.Calibrated("bar-1")
+	assert.True(t, ok)
+	led.SetColor(lightswarm.Color{R: 255, G: 255, B: 255})
+	expected := lightswarm.Frame{Addr: 690, Cmd: lightswarm.SET_RGB_LEVELS, CmdArgs: []byte{255, 208, 160}}
+	assert.Equal(t, expected.Bytes(), buff.Bytes())
+	buff.Reset()
+	led, ok = r.Calibrated("door")
+	assert.True(t, ok)
+	led.SetColor(lightswarm.Color{R: 255, G: 255, B: 255})
+	expected = lightswarm.Frame{Addr: 227, Cmd: lightswarm.SET_RGB_LEVELS, CmdArgs: []byte{255, 255, 255}}
+	assert.Equal(t, expected.Bytes(), buff.Bytes())
+	_, ok = r.Calibrated("missing")
+	assert.False(t, ok)
+}
+
+func TestRegistryZoneAndMembers(t *testing.T) {
+	r := venueRegistry(t, bytes.NewBuffer(nil))
+	addrs := []uint16{}
+	for _, led := range r.Zone("bar") {
+		addrs = append(addrs, led.Addr)
+	}
+	assert.Equal(t, []uint16{690, 691}, addrs)
+	assert.Empty(t, r.Zone("kitchen"))
+	assert.Equal(t, []uint16{690, 691}, r.Members(4096))
+	assert.Equal(t, []uint16{690}, r.Members(4097))
+	assert.Empty(t, r.Members(1))
+	fixtures := r.Fixtures()
+	assert.Equal(t, 227, fixtures[0].Addr)
+	assert.Equal(t, 691, fixtures[2].Addr)
+}
+
+func TestRegistryPushPseudoTables(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	r := venueRegistry(t, buff)
+	n, err := r.PushPseudoTables()
+	assert.Nil(t, err)
+	expected := bytes.NewBuffer(nil)
+	tracker := lightswarm.NewTracker(expected)
+	lightswarm.New(227, tracker).ErasePseudoAddressTable()
+	led := lightswarm.New(690, tracker)
+	led.ErasePseudoAddressTable()
+	led.SetPseudoAddress(4096)
+	led.SetPseudoAddress(4097)
+	led = lightswarm.New(691, tracker)
+	led.ErasePseudoAddressTable()
+	led.SetPseudoAddress(4096)
+	assert.Equal(t, expected.Len(), n)
+	assert.Equal(t, expected.Bytes(), buff.Bytes())
+	assert.Equal(t, []uint16{690, 691}, tracker.Members(4096))
+}
+
+func TestRegistryPushPseudoTablesError(t *testing.T) {
+	r := venueRegistry(t, nil)
+	r.Writer = errWriter{}
+	_, err := r.PushPseudoTables()
+	assert.EqualError(t, err, "boom")
+}
+
+func TestNewRegistryInvalid(t *testing.T) {
+	_, err := NewRegistry(Config{Fixtures: []Fixture{{Addr: 70000}}}, nil)
+	assert.IsType(t, &ValidationError{}, err)
+}
@@ -1,8 +1,17 @@
 package lightswarm
 
 import (
+	"context"
 	"encoding/binary"
+	"errors"
 	"io"
+	"time"
+)
+
+// Fade errors
+var (
+	ErrFadeTooFast = errors.New("lightswarm: fade duration shorter than the fastest fade")
+	ErrFadeTooSlow = errors.New("lightswarm: fade duration longer than the slowest fade")
 )
 
 // Byte constants
@@ -32,7 +41,12 @@ const (
 	FADE_RGB_TO_LEVEL          byte = 0x31 // fade rgb to level
 )
 
-// Helper for easily constructing Fade commands
+// Duration of a single fade interval
+const FadeInterval = time.Millisecond * 10
+
+// Helper for easily constructing Fade commands. Level and Interval are
+// 0-255 and Step 0-127, zero intervals and steps are sent as 1. Fades
+// out of range are rejected with ErrArgRange rather than clamped.
 type Fade struct {
 	Level    int
 	Interval int
@@ -68,8 +82,89 @@ func (f Fade) step() int {
 	return f.Step
 }
 
-// Command arguments
+// Returns the time taken to fade from the given level to the fade level
+func (f Fade) Duration(from byte) time.Duration {
+	diff := f.level() - int(from)
+	if diff < 0 {
+		diff = -diff
+	}
+	steps := (diff + f.step() - 1) / f.step()
+	return time.Duration(steps*f.interval()) * FadeInterval
+}
+
+// Fade timing limits, the interval is sent as a single byte
+const (
+	maxFadeInterval = 255
+	maxFadeStep     = 127
+)
+
+// Returns the fade from one level to another which best approximates the
+// duration and the duration it actually takes, preferring the smallest
+// step when several are equally close. Durations shorter than the
+// fastest possible fade return ErrFadeTooFast and longer than the slowest
+// return ErrFadeTooSlow, rather than being clamped.
+func FadeOver(from, to byte, d time.Duration) (Fade, time.Duration, error) {
+	diff := int(to) - int(from)
+	if diff < 0 {
+		diff = -diff
+	}
+	best := Fade{Level: int(to), Interval: 1, Step: 1}
+	if diff == 0 {
+		return best, 0, nil
+	}
+	maxStep := diff
+	if maxStep > maxFadeStep {
+		maxStep = maxFadeStep
+	}
+	fastest := time.Duration((diff+maxStep-1)/maxStep) * FadeInterval
+	slowest := time.Duration(diff*maxFadeInterval) * FadeInterval
+	switch {
+	case d < fastest:
+		return Fade{}, 0, ErrFadeTooFast
+	case d > slowest:
+		return Fade{}, 0, ErrFadeTooSlow
+	}
+	bestDiff := time.Duration(-1)
+	for step := 1; step <= maxStep; step++ {
+		steps := (diff + step - 1) / step
+		perStep := time.Duration(steps) * FadeInterval
+		interval := int((d + perStep/2) / perStep)
+		if interval < 1 {
+			interval = 1
+		}
+		if interval > maxFadeInterval {
+			interval = maxFadeInterval
+		}
+		f := Fade{Level: int(to), Interval: interval, Step: step}
+		off := f.Duration(from) - d
+		if off < 0 {
+			off = -off
+		}
+		if bestDiff < 0 || off < bestDiff {
+			best, bestDiff = f, off
+		}
+	}
+	return best, best.Duration(from), nil
+}
+
+// As FadeOver but durations too short for the change use the fastest fade
+// and those too long use the slowest, rather than returning an error
+func FadeNearest(from, to byte, d time.Duration) Fade {
+	f, _, err := FadeOver(from, to, d)
+	switch err {
+	case ErrFadeTooFast:
+		f = Fade{Level: int(to), Interval: 1, Step: maxFadeStep}
+	case ErrFadeTooSlow:
+		f = Fade{Level: int(to), Interval: maxFadeInterval, Step: 1}
+	}
+	return f
+}
+
+// Command arguments, nil if the fade is out of range
 func (f Fade) Args() []byte {
+	if f.validate() != nil {
+		return nil
+	}
 	return []byte{
 		byte(f.level()),
 		byte(f.interval()),
@@ -93,10 +188,16 @@ type Frame struct {
 	CmdArgs []byte
 }
 
+// Returns the given address broken into 2 bytes
+func addrBytes(addr uint16) []byte {
+	bs := make([]byte, 2)
+	binary.BigEndian.PutUint16(bs, addr)
+	return bs
+}
+
 // Returns the frame address broken into 2 bytes
 func (f Frame) address() (b1, b2 byte) {
-	bs := make([]byte, 2)
-	binary.BigEndian.PutUint16(bs, f.Addr)
+	bs := addrBytes(f.Addr)
 	return bs[0], bs[1]
 }
 
@@ -150,6 +251,35 @@ func (f Frame) Bytes() []byte {
 	return frame
 }
 
+// The command set shared by anything that can be addressed on the bus,
+// implemented by both LED and Group
+type Light interface {
+	On() (int, []byte, error)
+	Off() (int, []byte, error)
+	Toggle() (int, []byte, error)
+	SetLevel(level byte) (int, []byte, error)
+	FadeDown(f Fade) (int, []byte, error)
+	Fade(f Fade) (int, []byte, error)
+	SetRGB(r, g, b byte) (int, []byte, error)
+	FadeRGB(r, g, b Fade) (int, []byte, error)
+}
+
+// Light with the typed and context aware variants of each command, for
+// callers which need to abandon a command when their context is done
+type ContextLight interface {
+	Light
+	Send(cmd Command) (int, []byte, error)
+	SendContext(ctx context.Context, cmd Command) (int, []byte, error)
+	OnContext(ctx context.Context) (int, []byte, error)
+	OffContext(ctx context.Context) (int, []byte, error)
+	ToggleContext(ctx context.Context) (int, []byte, error)
+	SetLevelContext(ctx context.Context, level byte) (int, []byte, error)
+	FadeDownContext(ctx context.Context, f Fade) (int, []byte, error)
+	FadeContext(ctx context.Context, f Fade) (int, []byte, error)
+	SetRGBContext(ctx context.Context, r, g, b byte) (int, []byte, error)
+	FadeRGBContext(ctx context.Context, r, g, b Fade) (int, []byte, error)
+}
+
 // Represents a single Lightswarm LED
 type LED struct {
 	// Exported Fields
@@ -157,70 +287,163 @@ type LED struct {
 	Writer io.Writer
 }
 
-// Write to the lightswarm writer
-func (led *LED) write(frame Frame) (int, []byte, error) {
+// Write to the lightswarm writer, the write is abandoned if the context
+// is done before it starts, or while it is queued if the writer
+// implements ContextWriter
+func (led *LED) write(ctx context.Context, frame Frame) (int, []byte, error) {
 	b := frame.Bytes()
-	n, err := led.Writer.Write(b)
+	n, err := WriteContext(ctx, led.Writer, b)
 	if err != nil {
 		return 0, nil, err
 	}
 	return n, b, nil
 }
 
+// Send a typed command to the LED writer, the command arguments are
+// validated before anything is written
+func (led *LED) Send(cmd Command) (int, []byte, error) {
+	return led.SendContext(context.Background(), cmd)
+}
+
+// As Send but abandoned if the context is done before it is written
+func (led *LED) SendContext(ctx context.Context, cmd Command) (int, []byte, error) {
+	frame, err := NewFrame(led.Addr, cmd)
+	if err != nil {
+		return 0, nil, err
+	}
+	return led.write(ctx, frame)
+}
+
 // Send the On command to the LED writer
 func (led *LED) On() (int, []byte, error) {
-	frame := Frame{Addr: led.Addr, Cmd: ON}
-	return led.write(frame)
+	return led.OnContext(context.Background())
+}
+
+// As On but abandoned if the context is done before it is written
+func (led *LED) OnContext(ctx context.Context) (int, []byte, error) {
+	return led.SendContext(ctx, &On{})
 }
 
 // Send the Off command to the LED writer
 func (led *LED) Off() (int, []byte, error) {
-	frame := Frame{Addr: led.Addr, Cmd: OFF}
-	return led.write(frame)
+	return led.OffContext(context.Background())
+}
+
+// As Off but abandoned if the context is done before it is written
+func (led *LED) OffContext(ctx context.Context) (int, []byte, error) {
+	return led.SendContext(ctx, &Off{})
+}
+
+// Send the Toggle command to the LED writer
+func (led *LED) Toggle() (int, []byte, error) {
+	return led.ToggleContext(context.Background())
+}
+
+// As Toggle but abandoned if the context is done before it is written
+func (led *LED) ToggleContext(ctx context.Context) (int, []byte, error) {
+	return led.SendContext(ctx, &Toggle{})
+}
+
+// Set the light level immediately
+func (led *LED) SetLevel(level byte) (int, []byte, error) {
+	return led.SetLevelContext(context.Background(), level)
+}
+
+// As SetLevel but abandoned if the context is done before it is written
+func (led *LED) SetLevelContext(ctx context.Context, level byte) (int, []byte, error) {
+	return led.SendContext(ctx, &SetLevel{Level: level})
 }
 
-// Fade down legacy
+// Fade down legacy, ErrArgRange is returned for fades out of range
 func (led *LED) FadeDown(f Fade) (int, []byte, error) {
-	frame := Frame{
-		Addr:    led.Addr,
-		Cmd:     FADE_DOWN,
-		CmdArgs: f.Args(),
-	}
-	return led.write(frame)
+	return led.FadeDownContext(context.Background(), f)
+}
+
+// As FadeDown but abandoned if the context is done before it is written
+func (led *LED) FadeDownContext(ctx context.Context, f Fade) (int, []byte, error) {
+	return led.SendContext(ctx, &FadeDown{Fade: f})
 }
 
-// Fade to a light level
+// Fade to a light level, ErrArgRange is returned for fades out of range
 func (led *LED) Fade(f Fade) (int, []byte, error) {
-	frame := Frame{
-		Addr:    led.Addr,
-		Cmd:     FADE_TO_LEVEL,
-		CmdArgs: f.Args(),
-	}
-	return led.write(frame)
+	return led.FadeContext(context.Background(), f)
+}
+
+// As Fade but abandoned if the context is done before it is written
+func (led *LED) FadeContext(ctx context.Context, f Fade) (int, []byte, error) {
+	return led.SendContext(ctx, &FadeToLevel{Fade: f})
 }
 
 // Set Red, Green and Blue levels
 func (led *LED) SetRGB(r, g, b byte) (int, []byte, error) {
-	frame := Frame{
-		Addr:    led.Addr,
-		Cmd:     SET_RGB_LEVELS,
-		CmdArgs: []byte{r, g, b},
-	}
-	return led.write(frame)
+	return led.SetRGBContext(context.Background(), r, g, b)
 }
 
-// Fade to a RGB level
+// As SetRGB but abandoned if the context is done before it is written
+func (led *LED) SetRGBContext(ctx context.Context, r, g, b byte) (int, []byte, error) {
+	return led.SendContext(ctx, &SetRGBLevels{R: r, G: g, B: b})
+}
+
+// Fade to a RGB level, ErrArgRange is returned for fades out of range
 func (led *LED) FadeRGB(r, g, b Fade) (int, []byte, error) {
-	args := []byte{}
-	args = append(args, r.Args()...)
-	args = append(args, g.Args()...)
-	args = append(args, b.Args()...)
-	frame := Frame{
-		Addr:    led.Addr,
-		Cmd:     FADE_RGB_TO_LEVEL,
-		CmdArgs: args,
-	}
-	return led.write(frame)
+	return led.FadeRGBContext(context.Background(), r, g, b)
+}
+
+// As FadeRGB but abandoned if the context is done before it is written
+func (led *LED) FadeRGBContext(ctx context.Context, r, g, b Fade) (int, []byte, error) {
+	return led.SendContext(ctx, &FadeRGBToLevel{R: r, G: g, B: b})
+}
+
+// Set the colour, sent as given, wrap the LED in Calibrated to correct
+// colours first
+func (led *LED) SetColor(c Color) (int, []byte, error) {
+	return led.SetColorContext(context.Background(), c)
+}
+
+// As SetColor but abandoned if the context is done before it is written
+func (led *LED) SetColorContext(ctx context.Context, c Color) (int, []byte, error) {
+	return led.SetRGBContext(ctx, c.R, c.G, c.B)
+}
+
+// Fade to a colour with the same timing on every channel, sent as given,
+// wrap the LED in Calibrated to correct colours first
+func (led *LED) FadeColor(c Color, t Timing) (int, []byte, error) {
+	return led.FadeColorContext(context.Background(), c, t)
+}
+
+// As FadeColor but abandoned if the context is done before it is written
+func (led *LED) FadeColorContext(ctx context.Context, c Color, t Timing) (int, []byte, error) {
+	return led.FadeRGBContext(ctx, t.fade(c.R), t.fade(c.G), t.fade(c.B))
+}
+
+// Add a psuedo address to the LED's psuedo address table, the LED will
+// then also respond to frames sent to that address
+func (led *LED) SetPseudoAddress(addr uint16) (int, []byte, error) {
+	return led.SetPseudoAddressContext(context.Background(), addr)
+}
+
+// As SetPseudoAddress but abandoned if the context is done before it is written
+func (led *LED) SetPseudoAddressContext(ctx context.Context, addr uint16) (int, []byte, error) {
+	return led.SendContext(ctx, &SetPseudoAddress{Addr: addr})
+}
+
+// Erase all psuedo addresses from the LED's psuedo address table
+func (led *LED) ErasePseudoAddressTable() (int, []byte, error) {
+	return led.ErasePseudoAddressTableContext(context.Background())
+}
+
+// As ErasePseudoAddressTable but abandoned if the context is done before it is written
+func (led *LED) ErasePseudoAddressTableContext(ctx context.Context) (int, []byte, error) {
+	return led.SendContext(ctx, &ErasePseudoAddressTable{})
+}
+
+// Add the LED to the group at the given psuedo address and return a
+// Group for driving it and any other members
+func (led *LED) Join(addr uint16) (*Group, error) {
+	if _, _, err := led.SetPseudoAddress(addr); err != nil {
+		return nil, err
+	}
+	return NewGroup(addr, led.Writer), nil
 }
 
 // Constructs a new LED
@@ -5,6 +5,7 @@ import (
 	"io"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -20,6 +21,16 @@ func TestFadeArgs(t *testing.T) {
 			Fade{255, 1, 1},
 			[]byte{255, 1, 1},
 		},
+		{
+			"zero interval and step sent as 1",
+			Fade{255, 0, 0},
+			[]byte{255, 1, 1},
+		},
+		{
+			"level out of range",
+			Fade{300, 1, 1},
+			nil,
+		},
 	}
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
@@ -29,6 +40,156 @@ func TestFadeArgs(t *testing.T) {
 	}
 }
 
+func TestFadeDuration(t *testing.T) {
+	tt := []struct {
+		name     string
+		fade     Fade
+		from     byte
+		expected time.Duration
+	}{
+		{
+			"fade 0 to 255 at 1 step per 1 interval",
+			Fade{255, 1, 1},
+			0,
+			time.Millisecond * 2550,
+		},
+		{
+			"fade 255 to 0 at 5 step per 2 interval",
+			Fade{0, 2, 5},
+			255,
+			time.Millisecond * 1020,
+		},
+		{
+			"partial last step",
+			Fade{10, 1, 3},
+			0,
+			time.Millisecond * 40,
+		},
+		{
+			"already at level",
+			Fade{10, 1, 3},
+			10,
+			0,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.fade.Duration(tc.from))
+		})
+	}
+}
+
+func TestFadeOver(t *testing.T) {
+	tt := []struct {
+		name     string
+		from     byte
+		to       byte
+		d        time.Duration
+		expected Fade
+		achieved time.Duration
+		err      error
+	}{
+		{
+			"fade 0 to 255 over 2.55s",
+			0, 255,
+			time.Millisecond * 2550,
+			Fade{255, 1, 1},
+			time.Millisecond * 2550,
+			nil,
+		},
+		{
+			"fade 0 to 255 over 1s",
+			0, 255,
+			time.Second,
+			Fade{255, 5, 13},
+			time.Second,
+			nil,
+		},
+		{
+			"fade 200 to 100 over 5s",
+			200, 100,
+			time.Second * 5,
+			Fade{100, 5, 1},
+			time.Second * 5,
+			nil,
+		},
+		{
+			"approximated",
+			0, 100,
+			time.Millisecond * 1234,
+			Fade{100, 41, 34},
+			time.Millisecond * 1230,
+			nil,
+		},
+		{
+			"fastest fade",
+			255, 0,
+			time.Millisecond * 30,
+			Fade{0, 1, 85},
+			time.Millisecond * 30,
+			nil,
+		},
+		{
+			"already at level",
+			10, 10,
+			time.Second,
+			Fade{10, 1, 1},
+			0,
+			nil,
+		},
+		{
+			"too fast",
+			0, 255,
+			time.Millisecond * 20,
+			Fade{},
+			0,
+			ErrFadeTooFast,
+		},
+		{
+			"too slow",
+			0, 1,
+			time.Second * 3,
+			Fade{},
+			0,
+			ErrFadeTooSlow,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			f, achieved, err := FadeOver(tc.from, tc.to, tc.d)
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.expected, f)
+			assert.Equal(t, tc.achieved, achieved)
+			if err == nil {
+				assert.Equal(t, achieved, f.Duration(tc.from))
+			}
+		})
+	}
+}
+
+func TestFadeNearest(t *testing.T) {
+	tt := []struct {
+		name     string
+		from     byte
+		to       byte
+		d        time.Duration
+		expected time.Duration
+	}{
+		{"no transition", 0, 255, 0, time.Millisecond * 30},
+		{"one second", 0, 255, time.Second, time.Second},
+		{"ten seconds for a small change", 200, 255, time.Second * 10, time.Second * 10},
+		{"too fast", 0, 255, time.Millisecond * 10, time.Millisecond * 30},
+		{"too slow", 254, 255, time.Second * 10, time.Millisecond * 2550},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			f := FadeNearest(tc.from, tc.to, tc.d)
+			assert.Equal(t, int(tc.to), f.Level)
+			assert.InDelta(t, float64(tc.expected), float64(f.Duration(tc.from)), float64(tc.expected)/50)
+		})
+	}
+}
+
 func TestFrameAddress(t *testing.T) {
 	tt := []struct {
 		name    string
@@ -253,6 +414,89 @@ func TestLEDOff(t *testing.T) {
 	}
 }
 
+func TestLEDToggle(t *testing.T) {
+	tt := []struct {
+		name     string
+		addr     uint16
+		buff     *bytes.Buffer
+		expected []byte
+		n        int
+		err      error
+	}{
+		{
+			"toggle 690",
+			690,
+			bytes.NewBuffer(nil),
+			[]byte{END, 2, 178, TOGGLE, 157, END},
+			6,
+			nil,
+		},
+		{
+			"toggle 227",
+			227,
+			bytes.NewBuffer(nil),
+			[]byte{END, 0, 227, TOGGLE, 206, END},
+			6,
+			nil,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			led := &LED{tc.addr, tc.buff}
+			n, b, err := led.Toggle()
+			t.Logf("%s bytes: %v", tc.name, b)
+			assert.Equal(t, tc.n, n)
+			assert.Equal(t, tc.err, err)
+			bs, err := ioutil.ReadAll(tc.buff)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, bs)
+		})
+	}
+}
+
+func TestLEDSetLevel(t *testing.T) {
+	tt := []struct {
+		name     string
+		addr     uint16
+		buff     *bytes.Buffer
+		level    byte
+		expected []byte
+		n        int
+		err      error
+	}{
+		{
+			"set 690 level to 128",
+			690,
+			bytes.NewBuffer(nil),
+			128,
+			[]byte{END, 2, 178, SET_LEVEL, 128, 18, END},
+			7,
+			nil,
+		},
+		{
+			"set 690 level to 0",
+			690,
+			bytes.NewBuffer(nil),
+			0,
+			[]byte{END, 2, 178, SET_LEVEL, 0, 146, END},
+			7,
+			nil,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			led := &LED{tc.addr, tc.buff}
+			n, b, err := led.SetLevel(tc.level)
+			t.Logf("%s bytes: %v", tc.name, b)
+			assert.Equal(t, tc.n, n)
+			assert.Equal(t, tc.err, err)
+			bs, err := ioutil.ReadAll(tc.buff)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, bs)
+		})
+	}
+}
+
 func TestLEDFade(t *testing.T) {
 	tt := []struct {
 		name     string
@@ -395,6 +639,177 @@ func TestTestFadeRGB(t *testing.T) {
 	}
 }
 
+func TestLEDSetColor(t *testing.T) {
+	tt := []struct {
+		name        string
+		calibration *Calibration
+		color       Color
+		expected    []byte
+	}{
+		{
+			"set 690 colour to 85, 199, 237",
+			nil,
+			Color{85, 199, 237},
+			Frame{Addr: 690, Cmd: SET_RGB_LEVELS, CmdArgs: []byte{85, 199, 237}}.Bytes(),
+		},
+		{
+			"set 690 colour with white balance",
+			NewCalibration([3]float64{1, 1, 1}, Color{255, 128, 0}),
+			Color{100, 200, 50},
+			Frame{Addr: 690, Cmd: SET_RGB_LEVELS, CmdArgs: []byte{100, 100, 0}}.Bytes(),
+		},
+		{
+			"set 690 colour with gamma",
+			NewCalibration([3]float64{2, 2, 2}, Color{255, 255, 255}),
+			Color{255, 128, 0},
+			Frame{Addr: 690, Cmd: SET_RGB_LEVELS, CmdArgs: []byte{255, 64, 0}}.Bytes(),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			buff := bytes.NewBuffer(nil)
+			led := &LED{690, buff}
+			n, b, err := led.SetColor(tc.calibration.Apply(tc.color))
+			assert.Nil(t, err)
+			assert.Equal(t, len(tc.expected), n)
+			assert.Equal(t, tc.expected, b)
+			assert.Equal(t, tc.expected, buff.Bytes())
+		})
+	}
+}
+
+func TestLEDFadeColor(t *testing.T) {
+	tt := []struct {
+		name        string
+		calibration *Calibration
+		color       Color
+		timing      Timing
+		expected    []byte
+	}{
+		{
+			"fade 690 colour to 85, 199, 237",
+			nil,
+			Color{85, 199, 237},
+			Timing{Interval: 1, Step: 5},
+			Frame{Addr: 690, Cmd: FADE_RGB_TO_LEVEL, CmdArgs: []byte{85, 1, 5, 199, 1, 5, 237, 1, 5}}.Bytes(),
+		},
+		{
+			"fade 690 colour with white balance",
+			NewCalibration([3]float64{}, Color{255, 128, 0}),
+			Color{255, 255, 255},
+			Timing{Interval: 2, Step: 1},
+			Frame{Addr: 690, Cmd: FADE_RGB_TO_LEVEL, CmdArgs: []byte{255, 2, 1, 128, 2, 1, 0, 2, 1}}.Bytes(),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			buff := bytes.NewBuffer(nil)
+			led := &LED{690, buff}
+			n, b, err := led.FadeColor(tc.calibration.Apply(tc.color), tc.timing)
+			assert.Nil(t, err)
+			assert.Equal(t, len(tc.expected), n)
+			assert.Equal(t, tc.expected, b)
+			assert.Equal(t, tc.expected, buff.Bytes())
+		})
+	}
+}
+
+func TestLEDSetPseudoAddress(t *testing.T) {
+	tt := []struct {
+		name     string
+		addr     uint16
+		buff     *bytes.Buffer
+		pseudo   uint16
+		expected []byte
+		n        int
+		err      error
+	}{
+		{
+			"add 690 to psuedo address 4096",
+			690,
+			bytes.NewBuffer(nil),
+			4096,
+			[]byte{END, 2, 178, SET_PSUEDO_ADDRESS, 16, 0, 133, END},
+			8,
+			nil,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			led := &LED{tc.addr, tc.buff}
+			n, b, err := led.SetPseudoAddress(tc.pseudo)
+			t.Logf("%s bytes: %v", tc.name, b)
+			assert.Equal(t, tc.n, n)
+			assert.Equal(t, tc.err, err)
+			bs, err := ioutil.ReadAll(tc.buff)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, bs)
+		})
+	}
+}
+
+func TestLEDErasePseudoAddressTable(t *testing.T) {
+	tt := []struct {
+		name     string
+		addr     uint16
+		buff     *bytes.Buffer
+		expected []byte
+		n        int
+		err      error
+	}{
+		{
+			"erase 690 psuedo address table",
+			690,
+			bytes.NewBuffer(nil),
+			[]byte{END, 2, 178, ERASE_PSUEDO_ADDRESS_TABLE, 150, END},
+			6,
+			nil,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			led := &LED{tc.addr, tc.buff}
+			n, b, err := led.ErasePseudoAddressTable()
+			t.Logf("%s bytes: %v", tc.name, b)
+			assert.Equal(t, tc.n, n)
+			assert.Equal(t, tc.err, err)
+			bs, err := ioutil.ReadAll(tc.buff)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, bs)
+		})
+	}
+}
+
+func TestLEDJoin(t *testing.T) {
+	tt := []struct {
+		name     string
+		addr     uint16
+		buff     *bytes.Buffer
+		pseudo   uint16
+		expected []byte
+	}{
+		{
+			"690 joins psuedo address 4096",
+			690,
+			bytes.NewBuffer(nil),
+			4096,
+			[]byte{END, 2, 178, SET_PSUEDO_ADDRESS, 16, 0, 133, END},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			led := &LED{tc.addr, tc.buff}
+			group, err := led.Join(tc.pseudo)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.pseudo, group.Addr)
+			assert.Equal(t, tc.buff, group.Writer)
+			bs, err := ioutil.ReadAll(tc.buff)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, bs)
+		})
+	}
+}
+
 func TestNew(t *testing.T) {
 	tt := []struct {
 		name    string
@@ -0,0 +1,240 @@
+/*
+Package mqttbridge bridges MQTT to LightSwarm LED's, announcing each fixture
+to Home Assistant with MQTT discovery.
+
+For every fixture the bridge subscribes to a command topic and publishes a
+retained state topic and discovery payload using the Home Assistant JSON
+light schema:
+
+	lightswarm/690/set                         {"state": "ON", "brightness": 128, "color": {"r": 255, "g": 0, "b": 0}}
+	lightswarm/690/state                       {"state": "ON", "brightness": 128, ...}
+	homeassistant/light/lightswarm_690/config  discovery payload
+
+The bridge is not tied to an MQTT library, any client can be used by
+adapting it to the Client interface.
+*/
+package mqttbridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/thisissoon/lightswarm"
+)
+
+// Default topic prefixes
+const (
+	DefaultPrefix          = "lightswarm"
+	DefaultDiscoveryPrefix = "homeassistant"
+)
+
+// The subset of an MQTT client used by the bridge
+type Client interface {
+	Subscribe(topic string, handler func(topic string, payload []byte)) error
+	Publish(topic string, payload []byte, retained bool) error
+}
+
+// A fixture exposed over MQTT
+type Fixture struct {
+	Addr uint16
+	Name string
+	RGB  bool // Whether the fixture supports colour
+}
+
+// Colour in a command or state payload
+type Color struct {
+	R byte `json:"r"`
+	G byte `json:"g"`
+	B byte `json:"b"`
+}
+
+// Payload received on the command topic
+type Command struct {
+	State      string  `json:"state,omitempty"`
+	Brightness *byte   `json:"brightness,omitempty"`
+	Color      *Color  `json:"color,omitempty"`
+	Transition float64 `json:"transition,omitempty"` // seconds
+}
+
+// Payload published on the state topic
+type State struct {
+	State      string `json:"state"`
+	Brightness byte   `json:"brightness"`
+	ColorMode  string `json:"color_mode"`
+	Color      *Color `json:"color,omitempty"`
+}
+
+// Home Assistant MQTT light discovery payload
+type Discovery struct {
+	Name                string   `json:"name"`
+	UniqueID            string   `json:"unique_id"`
+	Schema              string   `json:"schema"`
+	CommandTopic        string   `json:"command_topic"`
+	StateTopic          string   `json:"state_topic"`
+	Brightness          bool     `json:"brightness"`
+	BrightnessScale     int      `json:"brightness_scale"`
+	SupportedColorModes []string `json:"supported_color_modes"`
+}
+
+// Bridges MQTT command topics to LED's written to Writer
+type Bridge struct {
+	// Exported Fields
+	Client          Client
+	Writer          io.Writer
+	Fixtures        []Fixture
+	Prefix          string      // Topic prefix, defaults to DefaultPrefix
+	DiscoveryPrefix string      // Discovery prefix, defaults to DefaultDiscoveryPrefix
+	ErrorLog        *log.Logger // Logs command errors, defaults to the log package
+
+	mu     sync.Mutex
+	states map[uint16]State
+}
+
+// Returns the topic prefix
+func (b *Bridge) prefix() string {
+	if b.Prefix == "" {
+		return DefaultPrefix
+	}
+	return b.Prefix
+}
+
+// Returns the discovery topic prefix
+func (b *Bridge) discoveryPrefix() string {
+	if b.DiscoveryPrefix == "" {
+		return DefaultDiscoveryPrefix
+	}
+	return b.DiscoveryPrefix
+}
+
+// Logs an error to ErrorLog or the standard logger
+func (b *Bridge) logf(format string, args ...interface{}) {
+	if b.ErrorLog != nil {
+		b.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// Returns the command topic for the address
+func (b *Bridge) commandTopic(addr uint16) string {
+	return fmt.Sprintf("%s/%d/set", b.prefix(), addr)
+}
+
+// Returns the state topic for the address
+func (b *Bridge) stateTopic(addr uint16) string {
+	return fmt.Sprintf("%s/%d/state", b.prefix(), addr)
+}
+
+// Returns the discovery topic and payload for the fixture
+func (b *Bridge) discovery(f Fixture) (string, Discovery) {
+	id := fmt.Sprintf("lightswarm_%d", f.Addr)
+	name := f.Name
+	if name == "" {
+		name = fmt.Sprintf("LightSwarm %d", f.Addr)
+	}
+	modes := []string{"brightness"}
+	if f.RGB {
+		modes = []string{"rgb"}
+	}
+	return fmt.Sprintf("%s/light/%s/config", b.discoveryPrefix(), id), Discovery{
+		Name:                name,
+		UniqueID:            id,
+		Schema:              "json",
+		CommandTopic:        b.commandTopic(f.Addr),
+		StateTopic:          b.stateTopic(f.Addr),
+		Brightness:          true,
+		BrightnessScale:     255,
+		SupportedColorModes: modes,
+	}
+}
+
+// Publishes a JSON payload
+func (b *Bridge) publish(topic string, v interface{}, retained bool) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Client.Publish(topic, payload, retained)
+}
+
+// Publishes the discovery payload and initial state of every fixture and
+// subscribes to their command topics
+func (b *Bridge) Start() error {
+	b.mu.Lock()
+	b.states = map[uint16]State{}
+	b.mu.Unlock()
+	for _, f := range b.Fixtures {
+		f := f
+		topic, discovery := b.discovery(f)
+		if err := b.publish(topic, discovery, true); err != nil {
+			return err
+		}
+		state := State{State: "OFF", ColorMode: discovery.SupportedColorModes[0]}
+		if f.RGB {
+			state.Color = &Color{}
+		}
+		b.mu.Lock()
+		b.states[f.Addr] = state
+		b.mu.Unlock()
+		if err := b.publish(b.stateTopic(f.Addr), state, true); err != nil {
+			return err
+		}
+		err := b.Client.Subscribe(b.commandTopic(f.Addr), func(topic string, payload []byte) {
+			if err := b.Handle(f, payload); err != nil {
+				b.logf("mqttbridge: %s: %s", topic, err)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Applies a command payload to the fixture and publishes its new state
+func (b *Bridge) Handle(f Fixture, payload []byte) error {
+	var cmd Command
+	if err := json.Unmarshal(payload, &cmd); err != nil {
+		return err
+	}
+	led := lightswarm.New(f.Addr, b.Writer)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.states == nil {
+		b.states = map[uint16]State{}
+	}
+	state := b.states[f.Addr]
+	if cmd.Color != nil {
+		if _, _, err := led.SetRGB(cmd.Color.R, cmd.Color.G, cmd.Color.B); err != nil {
+			return err
+		}
+		c := *cmd.Color
+		state.Color = &c
+	}
+	if cmd.Brightness != nil {
+		d := time.Duration(cmd.Transition * float64(time.Second))
+		fade := lightswarm.FadeNearest(state.Brightness, *cmd.Brightness, d)
+		if _, _, err := led.Fade(fade); err != nil {
+			return err
+		}
+		state.Brightness = *cmd.Brightness
+	}
+	switch cmd.State {
+	case "ON":
+		if _, _, err := led.On(); err != nil {
+			return err
+		}
+		state.State = "ON"
+	case "OFF":
+		if _, _, err := led.Off(); err != nil {
+			return err
+		}
+		state.State = "OFF"
+	}
+	b.states[f.Addr] = state
+	return b.publish(b.stateTopic(f.Addr), state, true)
+}
@@ -0,0 +1,229 @@
+package mqttbridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thisissoon/lightswarm"
+)
+
+// An in-process stand-in for an MQTT broker
+type broker struct {
+	mu       sync.Mutex
+	handlers map[string][]func(topic string, payload []byte)
+	retained map[string][]byte
+}
+
+func newBroker() *broker {
+	return &broker{
+		handlers: map[string][]func(string, []byte){},
+		retained: map[string][]byte{},
+	}
+}
+
+func (b *broker) Subscribe(topic string, handler func(topic string, payload []byte)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[topic] = append(b.handlers[topic], handler)
+	return nil
+}
+
+func (b *broker) Publish(topic string, payload []byte, retained bool) error {
+	b.mu.Lock()
+	if retained {
+		b.retained[topic] = payload
+	}
+	handlers := b.handlers[topic]
+	b.mu.Unlock()
+	for _, h := range handlers {
+		h(topic, payload)
+	}
+	return nil
+}
+
+// Returns the retained payload for a topic decoded into v
+func (b *broker) get(t *testing.T, topic string, v interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	payload, ok := b.retained[topic]
+	assert.True(t, ok, "no retained message on %s", topic)
+	assert.Nil(t, json.Unmarshal(payload, v))
+}
+
+// A client that fails every call
+type errClient struct{}
+
+func (errClient) Subscribe(string, func(string, []byte)) error {
+	return errors.New("disconnected")
+}
+
+func (errClient) Publish(string, []byte, bool) error {
+	return errors.New("disconnected")
+}
+
+func TestBridgeStart(t *testing.T) {
+	mqtt := newBroker()
+	b := &Bridge{
+		Client: mqtt,
+		Writer: ioutil.Discard,
+		Fixtures: []Fixture{
+			{Addr: 690, Name: "Bar", RGB: true},
+			{Addr: 227},
+		},
+	}
+	assert.Nil(t, b.Start())
+	var discovery Discovery
+	mqtt.get(t, "homeassistant/light/lightswarm_690/config", &discovery)
+	assert.Equal(t, Discovery{
+		Name:                "Bar",
+		UniqueID:            "lightswarm_690",
+		Schema:              "json",
+		CommandTopic:        "lightswarm/690/set",
+		StateTopic:          "lightswarm/690/state",
+		Brightness:          true,
+		BrightnessScale:     255,
+		SupportedColorModes: []string{"rgb"},
+	}, discovery)
+	mqtt.get(t, "homeassistant/light/lightswarm_227/config", &discovery)
+	assert.Equal(t, "LightSwarm 227", discovery.Name)
+	assert.Equal(t, []string{"brightness"}, discovery.SupportedColorModes)
+	var state State
+	mqtt.get(t, "lightswarm/690/state", &state)
+	assert.Equal(t, State{State: "OFF", ColorMode: "rgb", Color: &Color{}}, state)
+	var mono State
+	mqtt.get(t, "lightswarm/227/state", &mono)
+	assert.Equal(t, State{State: "OFF", ColorMode: "brightness"}, mono)
+}
+
+func TestBridgeStartPrefixes(t *testing.T) {
+	mqtt := newBroker()
+	b := &Bridge{
+		Client:          mqtt,
+		Writer:          ioutil.Discard,
+		Fixtures:        []Fixture{{Addr: 690}},
+		Prefix:          "venue/lights",
+		DiscoveryPrefix: "ha",
+	}
+	assert.Nil(t, b.Start())
+	var discovery Discovery
+	mqtt.get(t, "ha/light/lightswarm_690/config", &discovery)
+	assert.Equal(t, "venue/lights/690/set", discovery.CommandTopic)
+	assert.Equal(t, "venue/lights/690/state", discovery.StateTopic)
+}
+
+func TestBridgeStartError(t *testing.T) {
+	b := &Bridge{
+		Client:   errClient{},
+		Writer:   ioutil.Discard,
+		Fixtures: []Fixture{{Addr: 690}},
+	}
+	assert.EqualError(t, b.Start(), "disconnected")
+}
+
+func TestBridgeCommands(t *testing.T) {
+	tt := []struct {
+		name     string
+		payload  string
+		expected []lightswarm.Frame
+		state    State
+	}{
+		{
+			"on",
+			`{"state": "ON"}`,
+			[]lightswarm.Frame{{Addr: 690, Cmd: lightswarm.ON}},
+			State{State: "ON", ColorMode: "rgb", Color: &Color{}},
+		},
+		{
+			"off",
+			`{"state": "OFF"}`,
+			[]lightswarm.Frame{{Addr: 690, Cmd: lightswarm.OFF}},
+			State{State: "OFF", ColorMode: "rgb", Color: &Color{}},
+		},
+		{
+			"brightness",
+			`{"state": "ON", "brightness": 128}`,
+			[]lightswarm.Frame{
+				{Addr: 690, Cmd: lightswarm.FADE_TO_LEVEL, CmdArgs: []byte{128, 1, 127}},
+				{Addr: 690, Cmd: lightswarm.ON},
+			},
+			State{State: "ON", Brightness: 128, ColorMode: "rgb", Color: &Color{}},
+		},
+		{
+			"brightness with transition",
+			`{"brightness": 255, "transition": 1}`,
+			[]lightswarm.Frame{
+				{Addr: 690, Cmd: lightswarm.FADE_TO_LEVEL, CmdArgs: []byte{255, 5, 13}},
+			},
+			State{State: "OFF", Brightness: 255, ColorMode: "rgb", Color: &Color{}},
+		},
+		{
+			"brightness with ten second transition",
+			`{"brightness": 255, "transition": 10}`,
+			[]lightswarm.Frame{
+				{Addr: 690, Cmd: lightswarm.FADE_TO_LEVEL, CmdArgs: []byte{255, 50, 13}},
+			},
+			State{State: "OFF", Brightness: 255, ColorMode: "rgb", Color: &Color{}},
+		},
+		{
+			"color",
+			`{"state": "ON", "color": {"r": 255, "g": 1, "b": 2}}`,
+			[]lightswarm.Frame{
+				{Addr: 690, Cmd: lightswarm.SET_RGB_LEVELS, CmdArgs: []byte{255, 1, 2}},
+				{Addr: 690, Cmd: lightswarm.ON},
+			},
+			State{State: "ON", ColorMode: "rgb", Color: &Color{255, 1, 2}},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mqtt := newBroker()
+			buff := bytes.NewBuffer(nil)
+			b := &Bridge{
+				Client:   mqtt,
+				Writer:   buff,
+				Fixtures: []Fixture{{Addr: 690, RGB: true}},
+			}
+			assert.Nil(t, b.Start())
+			assert.Nil(t, mqtt.Publish("lightswarm/690/set", []byte(tc.payload), false))
+			expected := []byte{}
+			for _, f := range tc.expected {
+				expected = append(expected, f.Bytes()...)
+			}
+			assert.Equal(t, expected, buff.Bytes())
+			var state State
+			mqtt.get(t, "lightswarm/690/state", &state)
+			assert.Equal(t, tc.state, state)
+		})
+	}
+}
+
+func TestBridgeCommandError(t *testing.T) {
+	mqtt := newBroker()
+	logs := bytes.NewBuffer(nil)
+	b := &Bridge{
+		Client:   mqtt,
+		Writer:   ioutil.Discard,
+		Fixtures: []Fixture{{Addr: 690}},
+		ErrorLog: log.New(logs, "", 0),
+	}
+	assert.Nil(t, b.Start())
+	mqtt.Publish("lightswarm/690/set", []byte(`{`), false)
+	assert.Equal(t, "mqttbridge: lightswarm/690/set: unexpected end of JSON input\n", logs.String())
+}
+
+func TestBridgeHandleBeforeStart(t *testing.T) {
+	mqtt := newBroker()
+	buff := bytes.NewBuffer(nil)
+	b := &Bridge{Client: mqtt, Writer: buff}
+	assert.Nil(t, b.Handle(Fixture{Addr: 690}, []byte(`{"state": "ON"}`)))
+	assert.Equal(t, lightswarm.Frame{Addr: 690, Cmd: lightswarm.ON}.Bytes(), buff.Bytes())
+	var state State
+	mqtt.get(t, "lightswarm/690/state", &state)
+	assert.Equal(t, "ON", state.State)
+}
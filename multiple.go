@@ -0,0 +1,75 @@
+package lightswarm
+
+import (
+	"context"
+	"io"
+)
+
+// Number of argument bytes per fade in a FADE_MULTIPLE_TO_LEVEL frame,
+// 2 address bytes followed by the level, interval and step
+const multipleFadeLen = 5
+
+// Maximum number of fades encoded into a single FADE_MULTIPLE_TO_LEVEL
+// frame, as many as fit in the arguments of a MaxFrameLen frame (16)
+const MaxMultipleFades = (MaxFrameLen - minFrameLen) / multipleFadeLen
+
+// A Fade targeted at a single address, used to batch fades for many
+// addresses into FADE_MULTIPLE_TO_LEVEL frames
+type AddressFade struct {
+	Addr uint16
+	Fade Fade
+}
+
+// Command arguments, the address bytes followed by the fade arguments
+func (af AddressFade) Args() []byte {
+	return append(addrBytes(af.Addr), af.Fade.Args()...)
+}
+
+// Builds the FADE_MULTIPLE_TO_LEVEL frames for the given fades, using as
+// few frames as possible. Every node must inspect the arguments for its
+// own address, so frames are sent to addr which should be an address all
+// of them receive, such as the installation's all call address.
+// ErrArgRange is returned if any fade is out of range.
+func FadeMultipleFrames(addr uint16, fades ...AddressFade) ([]Frame, error) {
+	frames := []Frame{}
+	for len(fades) > 0 {
+		n := len(fades)
+		if n > MaxMultipleFades {
+			n = MaxMultipleFades
+		}
+		frame, err := NewFrame(addr, &FadeMultipleToLevel{Fades: fades[:n]})
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+		fades = fades[n:]
+	}
+	return frames, nil
+}
+
+// Fade many addresses at once, the frames are written in a single burst
+// so the fades start together rather than rippling across the fixtures.
+// Frames are sent to addr as with FadeMultipleFrames.
+func FadeMultiple(writer io.Writer, addr uint16, fades ...AddressFade) (int, []byte, error) {
+	return FadeMultipleContext(context.Background(), writer, addr, fades...)
+}
+
+// As FadeMultiple but abandoned if the context is done before it is written
+func FadeMultipleContext(ctx context.Context, writer io.Writer, addr uint16, fades ...AddressFade) (int, []byte, error) {
+	frames, err := FadeMultipleFrames(addr, fades...)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(frames) == 0 {
+		return 0, nil, nil
+	}
+	b := []byte{}
+	for _, frame := range frames {
+		b = append(b, frame.Bytes()...)
+	}
+	n, err := WriteContext(ctx, writer, b)
+	if err != nil {
+		return 0, nil, err
+	}
+	return n, b, nil
+}
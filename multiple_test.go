@@ -0,0 +1,133 @@
+package lightswarm
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns n fades to full at 1 step per 1 interval for consecutive addresses
+func fades(n int) []AddressFade {
+	fs := make([]AddressFade, n)
+	for i := range fs {
+		fs[i] = AddressFade{uint16(i + 1), Fade{255, 1, 1}}
+	}
+	return fs
+}
+
+// Returns the FadeMultipleFrames frames, failing the test on error
+func multipleFrames(t *testing.T, addr uint16, fades ...AddressFade) []Frame {
+	frames, err := FadeMultipleFrames(addr, fades...)
+	assert.Nil(t, err)
+	return frames
+}
+
+func TestAddressFadeArgs(t *testing.T) {
+	tt := []struct {
+		name     string
+		fade     AddressFade
+		expected []byte
+	}{
+		{
+			"fade 690 to 255 at 1 step per 1 interval",
+			AddressFade{690, Fade{255, 1, 1}},
+			[]byte{2, 178, 255, 1, 1},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.fade.Args())
+		})
+	}
+}
+
+func TestFadeMultipleFrames(t *testing.T) {
+	tt := []struct {
+		name   string
+		fades  []AddressFade
+		frames int
+		last   int // number of fades in the last frame
+	}{
+		{"no fades", fades(0), 0, 0},
+		{"single fade", fades(1), 1, 1},
+		{"full frame", fades(MaxMultipleFades), 1, MaxMultipleFades},
+		{"overflow frame", fades(MaxMultipleFades + 1), 2, 1},
+		{"many frames", fades(MaxMultipleFades*3 + 5), 4, 5},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			frames, err := FadeMultipleFrames(4095, tc.fades...)
+			assert.NoError(t, err)
+			assert.Len(t, frames, tc.frames)
+			for _, f := range frames {
+				assert.Equal(t, uint16(4095), f.Addr)
+				assert.Equal(t, FADE_MULTIPLE_TO_LEVEL, f.Cmd)
+			}
+			if tc.frames > 0 {
+				assert.Len(t, frames[len(frames)-1].CmdArgs, tc.last*5)
+			}
+		})
+	}
+}
+
+func TestMaxMultipleFades(t *testing.T) {
+	assert.Equal(t, 16, MaxMultipleFades)
+	frames, err := FadeMultipleFrames(4095, fades(MaxMultipleFades)...)
+	assert.NoError(t, err)
+	assert.Equal(t, MaxFrameLen, minFrameLen+len(frames[0].CmdArgs))
+}
+
+func TestFadeMultiple(t *testing.T) {
+	tt := []struct {
+		name     string
+		buff     *bytes.Buffer
+		fades    []AddressFade
+		expected []byte
+		n        int
+		err      error
+	}{
+		{
+			"fade 690 and 227",
+			bytes.NewBuffer(nil),
+			[]AddressFade{
+				{690, Fade{255, 1, 1}},
+				{227, Fade{128, 2, 3}},
+			},
+			[]byte{END, 15, 255, FADE_MULTIPLE_TO_LEVEL, 2, 178, 255, 1, 1, 0, 227, 128, 2, 3, 237, END},
+			16,
+			nil,
+		},
+		{
+			"no fades",
+			bytes.NewBuffer(nil),
+			nil,
+			[]byte{},
+			0,
+			nil,
+		},
+		{
+			"interval out of range",
+			bytes.NewBuffer(nil),
+			[]AddressFade{
+				{690, Fade{255, 1, 1}},
+				{1, Fade{10, 300, 1}},
+			},
+			[]byte{},
+			0,
+			ErrArgRange,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			n, b, err := FadeMultiple(tc.buff, 4095, tc.fades...)
+			t.Logf("%s bytes: %v", tc.name, b)
+			assert.Equal(t, tc.n, n)
+			assert.Equal(t, tc.err, err)
+			bs, err := ioutil.ReadAll(tc.buff)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, bs)
+		})
+	}
+}
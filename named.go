@@ -0,0 +1,45 @@
+package lightswarm
+
+// A command addressed by a short name with one byte per argument, shared
+// by lightswarmctl and schedules so both accept the same commands
+type NamedCommand struct {
+	// Exported Fields
+	Name   string
+	Opcode byte
+	Args   []string // Argument names, in frame order
+}
+
+// Named commands, fade arguments are level, interval and step
+var namedCommands = []NamedCommand{
+	{"on", ON, nil},
+	{"off", OFF, nil},
+	{"toggle", TOGGLE, nil},
+	{"level", SET_LEVEL, []string{"level"}},
+	{"fade", FADE_TO_LEVEL, []string{"level", "interval", "step"}},
+	{"rgb", SET_RGB_LEVELS, []string{"red", "green", "blue"}},
+	{"fade-rgb", FADE_RGB_TO_LEVEL, []string{
+		"red", "interval", "step",
+		"green", "interval", "step",
+		"blue", "interval", "step",
+	}},
+}
+
+// Returns the named command: on, off, toggle, level, fade, rgb or
+// fade-rgb
+func LookupCommand(name string) (NamedCommand, bool) {
+	for _, c := range namedCommands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return NamedCommand{}, false
+}
+
+// Decodes the arguments into a typed command, ErrArgs is returned for the
+// wrong number of arguments and ErrArgRange for fades the LED's reject
+func (c NamedCommand) Command(args []byte) (Command, error) {
+	if len(args) != len(c.Args) {
+		return nil, ErrArgs
+	}
+	return Frame{Cmd: c.Opcode, CmdArgs: args}.Command()
+}
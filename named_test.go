@@ -0,0 +1,71 @@
+package lightswarm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamedCommand(t *testing.T) {
+	tt := []struct {
+		name        string
+		args        []byte
+		expected    Command
+		expectedErr error
+	}{
+		{
+			"on",
+			nil,
+			&On{},
+			nil,
+		},
+		{
+			"level",
+			[]byte{128},
+			&SetLevel{Level: 128},
+			nil,
+		},
+		{
+			"fade",
+			[]byte{255, 1, 5},
+			&FadeToLevel{Fade: Fade{Level: 255, Interval: 1, Step: 5}},
+			nil,
+		},
+		{
+			"fade-rgb",
+			[]byte{85, 1, 1, 199, 2, 2, 237, 3, 3},
+			&FadeRGBToLevel{
+				R: Fade{Level: 85, Interval: 1, Step: 1},
+				G: Fade{Level: 199, Interval: 2, Step: 2},
+				B: Fade{Level: 237, Interval: 3, Step: 3},
+			},
+			nil,
+		},
+		{
+			"level",
+			nil,
+			nil,
+			ErrArgs,
+		},
+		{
+			"fade",
+			[]byte{255, 1, 128},
+			nil,
+			ErrArgRange,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			named, ok := LookupCommand(tc.name)
+			assert.True(t, ok)
+			cmd, err := named.Command(tc.args)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expected, cmd)
+		})
+	}
+}
+
+func TestLookupCommandUnknown(t *testing.T) {
+	_, ok := LookupCommand("dim")
+	assert.False(t, ok)
+}
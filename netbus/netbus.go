@@ -0,0 +1,292 @@
+/*
+Package netbus carries LightSwarm frames over TCP, so the serial dongle can
+live on a different machine to the application driving it.
+
+On the machine with the dongle a Server forwards valid frames received from
+any number of clients to the serial port:
+
+	s := netbus.NewServer(serialPort)
+	log.Fatal(s.ListenAndServe(":7890"))
+
+The application writes frames through a Client, which implements io.Writer
+so it can be used anywhere a serial port would be:
+
+	c := netbus.NewClient("pi.local:7890")
+	led := lightswarm.New(690, c)
+	led.On()
+*/
+package netbus
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/thisissoon/lightswarm"
+)
+
+// Returned when using a closed Client or Server
+var ErrClosed = errors.New("netbus: closed")
+
+// Receives frames from TCP clients and forwards valid frames to Writer.
+// Frames failing to decode, including those with a bad checksum, are
+// logged and dropped.
+type Server struct {
+	// Exported Fields
+	Writer   io.Writer
+	ErrorLog *log.Logger // Logs dropped frames, defaults to the log package
+
+	mu        sync.Mutex // held while writing a frame
+	lmu       sync.Mutex
+	listeners map[net.Listener]bool
+	conns     map[net.Conn]bool
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// Creates the listener and connection maps, so a Server built as a
+// literal is usable. Called with lmu held.
+func (s *Server) init() {
+	if s.listeners == nil {
+		s.listeners = map[net.Listener]bool{}
+	}
+	if s.conns == nil {
+		s.conns = map[net.Conn]bool{}
+	}
+}
+
+// Logs to ErrorLog or the standard logger
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// Tracks a connection, returning false if the server is closed
+func (s *Server) track(conn net.Conn) bool {
+	s.lmu.Lock()
+	defer s.lmu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.init()
+	s.conns[conn] = true
+	s.wg.Add(1)
+	return true
+}
+
+// Stops tracking a connection
+func (s *Server) untrack(conn net.Conn) {
+	s.lmu.Lock()
+	defer s.lmu.Unlock()
+	delete(s.conns, conn)
+	s.wg.Done()
+}
+
+// Forwards frames from a single client until it disconnects
+func (s *Server) handle(conn net.Conn) {
+	defer s.untrack(conn)
+	defer conn.Close()
+	r := lightswarm.NewReader(conn)
+	for {
+		frame, err := r.ReadFrame()
+		switch err.(type) {
+		case nil:
+		case *lightswarm.FrameError:
+			s.logf("netbus: %s: dropped frame: %s", conn.RemoteAddr(), err)
+			continue
+		default:
+			if err != io.EOF && !s.isClosed() {
+				s.logf("netbus: %s: %s", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		s.mu.Lock()
+		_, err = s.Writer.Write(frame.Bytes())
+		s.mu.Unlock()
+		if err != nil {
+			s.logf("netbus: %s: write: %s", conn.RemoteAddr(), err)
+		}
+	}
+}
+
+// Reports whether the server has been closed
+func (s *Server) isClosed() bool {
+	s.lmu.Lock()
+	defer s.lmu.Unlock()
+	return s.closed
+}
+
+// Accepts connections on the listener until it fails or the server is
+// closed, in which case ErrClosed is returned
+func (s *Server) Serve(l net.Listener) error {
+	s.lmu.Lock()
+	if s.closed {
+		s.lmu.Unlock()
+		l.Close()
+		return ErrClosed
+	}
+	s.init()
+	s.listeners[l] = true
+	s.lmu.Unlock()
+	defer func() {
+		s.lmu.Lock()
+		delete(s.listeners, l)
+		s.lmu.Unlock()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrClosed
+			}
+			return err
+		}
+		if !s.track(conn) {
+			conn.Close()
+			return ErrClosed
+		}
+		go s.handle(conn)
+	}
+}
+
+// Listens on the TCP address and serves connections
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Closes all listeners and connections, waiting for in progress frames
+func (s *Server) Close() error {
+	s.lmu.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.lmu.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+// Constructs a new Server forwarding frames to the given writer
+func NewServer(writer io.Writer) *Server {
+	return &Server{
+		Writer: writer,
+	}
+}
+
+// Writes frames to a netbus Server, connecting on first use and
+// reconnecting if the connection drops
+type Client struct {
+	// Exported Fields
+	Addr    string
+	Timeout time.Duration // Dial and write timeout, 0 is no timeout
+	Retries int           // Reconnect attempts per write, defaults to 1
+
+	mu     sync.Mutex
+	conn   net.Conn
+	dead   chan struct{} // closed once conn has been closed by the server
+	closed bool
+}
+
+// Reads from the connection until it fails, then closes dead. The server
+// never writes to clients, so this only returns once the server has
+// closed or reset the connection.
+func watch(conn net.Conn, dead chan struct{}) {
+	io.Copy(ioutil.Discard, conn)
+	close(dead)
+}
+
+// Returns the number of reconnect attempts per write
+func (c *Client) retries() int {
+	if c.Retries < 1 {
+		return 1
+	}
+	return c.Retries
+}
+
+// Connects to the server if not already connected. A connection the
+// server has closed is replaced before writing, writes to it would be
+// accepted by the socket buffer and silently lost.
+func (c *Client) connect() error {
+	if c.conn != nil {
+		select {
+		case <-c.dead:
+			c.conn.Close()
+			c.conn = nil
+		default:
+			return nil
+		}
+	}
+	conn, err := net.DialTimeout("tcp", c.Addr, c.Timeout)
+	if err != nil {
+		return err
+	}
+	c.conn, c.dead = conn, make(chan struct{})
+	go watch(conn, c.dead)
+	return nil
+}
+
+// Writes to the current connection
+func (c *Client) write(p []byte) (int, error) {
+	if err := c.connect(); err != nil {
+		return 0, err
+	}
+	if c.Timeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.Timeout))
+	}
+	n, err := c.conn.Write(p)
+	if err != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+	return n, err
+}
+
+// Writes to the server, reconnecting and resending if the write fails.
+// The server resynchronises on END bytes so a partially sent frame is
+// dropped rather than corrupting the resent one.
+func (c *Client) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0, ErrClosed
+	}
+	n, err := c.write(p)
+	for i := 0; err != nil && i < c.retries(); i++ {
+		n, err = c.write(p)
+	}
+	return n, err
+}
+
+// Closes the connection to the server
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// Constructs a new Client for the server at the given TCP address
+func NewClient(addr string) *Client {
+	return &Client{
+		Addr: addr,
+	}
+}
@@ -0,0 +1,225 @@
+package netbus
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thisissoon/lightswarm"
+)
+
+// A buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte{}, b.buf.Bytes()...)
+}
+
+// Waits for the buffer to hold the expected bytes
+func waitFor(t *testing.T, b *syncBuffer, expected []byte) {
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		if bytes.Equal(b.Bytes(), expected) {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, expected, b.Bytes())
+}
+
+// Starts a server on a random local port
+func serve(t *testing.T, s *Server) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	return l.Addr().String()
+}
+
+func TestServerForwardsFrames(t *testing.T) {
+	buff := &syncBuffer{}
+	s := NewServer(buff)
+	s.ErrorLog = log.New(ioutil.Discard, "", 0)
+	defer s.Close()
+	c := NewClient(serve(t, s))
+	defer c.Close()
+	led := lightswarm.New(690, c)
+	_, _, err := led.On()
+	assert.Nil(t, err)
+	_, _, err = led.SetRGB(85, 199, 237)
+	assert.Nil(t, err)
+	expected := append(lightswarm.Frame{Addr: 690, Cmd: lightswarm.ON}.Bytes(),
+		lightswarm.Frame{Addr: 690, Cmd: lightswarm.SET_RGB_LEVELS, CmdArgs: []byte{85, 199, 237}}.Bytes()...)
+	waitFor(t, buff, expected)
+}
+
+func TestServerZeroValue(t *testing.T) {
+	buff := &syncBuffer{}
+	s := &Server{Writer: buff, ErrorLog: log.New(ioutil.Discard, "", 0)}
+	defer s.Close()
+	c := NewClient(serve(t, s))
+	defer c.Close()
+	_, _, err := lightswarm.New(690, c).On()
+	assert.Nil(t, err)
+	waitFor(t, buff, lightswarm.Frame{Addr: 690, Cmd: lightswarm.ON}.Bytes())
+}
+
+func TestServerDropsInvalidFrames(t *testing.T) {
+	buff := &syncBuffer{}
+	logs := &syncBuffer{}
+	s := NewServer(buff)
+	s.ErrorLog = log.New(logs, "", 0)
+	defer s.Close()
+	c := NewClient(serve(t, s))
+	defer c.Close()
+	// bad checksum, garbage then a valid frame
+	_, err := c.Write([]byte{lightswarm.END, 2, 178, lightswarm.ON, 145, lightswarm.END, 1, 2, 3})
+	assert.Nil(t, err)
+	on := lightswarm.Frame{Addr: 690, Cmd: lightswarm.ON}.Bytes()
+	_, err = c.Write(on)
+	assert.Nil(t, err)
+	waitFor(t, buff, on)
+	assert.Contains(t, string(logs.Bytes()), "dropped frame: lightswarm: bad frame checksum")
+	assert.Contains(t, string(logs.Bytes()), "dropped frame: lightswarm: truncated frame")
+}
+
+func TestServerMultipleClients(t *testing.T) {
+	buff := &syncBuffer{}
+	s := NewServer(buff)
+	defer s.Close()
+	addr := serve(t, s)
+	const clients, frames = 5, 20
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(addr uint16, c *Client) {
+			defer wg.Done()
+			defer c.Close()
+			led := lightswarm.New(addr, c)
+			for j := 0; j < frames; j++ {
+				led.SetLevel(byte(j))
+			}
+		}(uint16(i+1), NewClient(addr))
+	}
+	wg.Wait()
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) && len(buff.Bytes()) < clients*frames*7 {
+		time.Sleep(time.Millisecond)
+	}
+	r := lightswarm.NewReader(bytes.NewReader(buff.Bytes()))
+	count := 0
+	for {
+		f, err := r.ReadFrame()
+		if err != nil {
+			break
+		}
+		assert.Equal(t, lightswarm.SET_LEVEL, f.Cmd)
+		count++
+	}
+	assert.Equal(t, clients*frames, count)
+}
+
+func TestServerClose(t *testing.T) {
+	s := NewServer(ioutil.Discard)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	done := make(chan error)
+	go func() { done <- s.Serve(l) }()
+	c := NewClient(l.Addr().String())
+	defer c.Close()
+	_, err = c.Write(lightswarm.Frame{Addr: 690, Cmd: lightswarm.ON}.Bytes())
+	assert.Nil(t, err)
+	assert.Nil(t, s.Close())
+	assert.Equal(t, ErrClosed, <-done)
+	l, err = net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	assert.Equal(t, ErrClosed, s.Serve(l))
+}
+
+func TestClientReconnects(t *testing.T) {
+	buff := &syncBuffer{}
+	s := NewServer(buff)
+	defer s.Close()
+	c := NewClient(serve(t, s))
+	defer c.Close()
+	on := lightswarm.Frame{Addr: 690, Cmd: lightswarm.ON}.Bytes()
+	_, err := c.Write(on)
+	assert.Nil(t, err)
+	waitFor(t, buff, on)
+	// drop the connection
+	c.mu.Lock()
+	c.conn.Close()
+	c.mu.Unlock()
+	n, err := c.Write(on)
+	assert.Nil(t, err)
+	assert.Equal(t, len(on), n)
+	waitFor(t, buff, append(append([]byte{}, on...), on...))
+}
+
+func TestClientReconnectsAfterServerRestart(t *testing.T) {
+	buff := &syncBuffer{}
+	s := NewServer(buff)
+	addr := serve(t, s)
+	c := NewClient(addr)
+	defer c.Close()
+	on := lightswarm.Frame{Addr: 690, Cmd: lightswarm.ON}.Bytes()
+	_, err := c.Write(on)
+	assert.Nil(t, err)
+	waitFor(t, buff, on)
+	// the server closes the connection from its side
+	assert.Nil(t, s.Close())
+	c.mu.Lock()
+	dead := c.dead
+	c.mu.Unlock()
+	select {
+	case <-dead:
+	case <-time.After(time.Second * 5):
+		t.Fatal("client did not notice the server closing the connection")
+	}
+	restarted := &syncBuffer{}
+	s = NewServer(restarted)
+	defer s.Close()
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	off := lightswarm.Frame{Addr: 690, Cmd: lightswarm.OFF}.Bytes()
+	n, err := c.Write(off)
+	assert.Nil(t, err)
+	assert.Equal(t, len(off), n)
+	waitFor(t, restarted, off)
+}
+
+func TestClientErrors(t *testing.T) {
+	// find a free port with nothing listening on it
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := l.Addr().String()
+	l.Close()
+	c := NewClient(addr)
+	c.Timeout = time.Second
+	_, err = c.Write([]byte{lightswarm.END})
+	_, ok := err.(*net.OpError)
+	assert.True(t, ok)
+	assert.Nil(t, c.Close())
+	_, err = c.Write([]byte{lightswarm.END})
+	assert.Equal(t, ErrClosed, err)
+}
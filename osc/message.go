@@ -0,0 +1,197 @@
+package osc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Identifies a bundle rather than a message
+const bundleID = "#bundle"
+
+// Packet errors
+var (
+	ErrInvalidPacket = errors.New("osc: invalid packet")
+	ErrUnsupported   = errors.New("osc: unsupported argument type")
+)
+
+// An OSC message. Arguments are int32, float32, string, []byte (blob) or
+// bool.
+type Message struct {
+	Address string
+	Args    []interface{}
+}
+
+// Returns the OSC encoding of the message
+func (m Message) MarshalBinary() ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	tags := []byte{','}
+	args := bytes.NewBuffer(nil)
+	for _, arg := range m.Args {
+		switch v := arg.(type) {
+		case int32:
+			tags = append(tags, 'i')
+			binary.Write(args, binary.BigEndian, v)
+		case float32:
+			tags = append(tags, 'f')
+			binary.Write(args, binary.BigEndian, math.Float32bits(v))
+		case string:
+			tags = append(tags, 's')
+			writeString(args, v)
+		case []byte:
+			tags = append(tags, 'b')
+			binary.Write(args, binary.BigEndian, int32(len(v)))
+			args.Write(v)
+			args.Write(make([]byte, pad(len(v))-len(v)))
+		case bool:
+			if v {
+				tags = append(tags, 'T')
+			} else {
+				tags = append(tags, 'F')
+			}
+		default:
+			return nil, ErrUnsupported
+		}
+	}
+	writeString(buf, m.Address)
+	writeString(buf, string(tags))
+	buf.Write(args.Bytes())
+	return buf.Bytes(), nil
+}
+
+// Returns the message as it would be written in OSC documentation,
+// for example /lightswarm/690/rgb 1 0.5 0
+func (m Message) String() string {
+	s := m.Address
+	for _, arg := range m.Args {
+		s += fmt.Sprintf(" %v", arg)
+	}
+	return s
+}
+
+// Returns n rounded up to a multiple of 4
+func pad(n int) int {
+	return (n + 3) &^ 3
+}
+
+// Writes a null terminated string padded to a multiple of 4 bytes
+func writeString(buf *bytes.Buffer, s string) {
+	buf.WriteString(s)
+	buf.Write(make([]byte, pad(len(s)+1)-len(s)))
+}
+
+// Reads a padded string, returning the remaining bytes
+func readString(p []byte) (string, []byte, error) {
+	end := bytes.IndexByte(p, 0)
+	if end < 0 {
+		return "", nil, ErrInvalidPacket
+	}
+	n := pad(end + 1)
+	if n > len(p) {
+		return "", nil, ErrInvalidPacket
+	}
+	return string(p[:end]), p[n:], nil
+}
+
+// Parses a single OSC message
+func parseMessage(p []byte) (Message, error) {
+	address, p, err := readString(p)
+	if err != nil {
+		return Message{}, err
+	}
+	if len(address) == 0 || address[0] != '/' {
+		return Message{}, ErrInvalidPacket
+	}
+	m := Message{Address: address}
+	if len(p) == 0 {
+		// Older implementations omit the type tag string
+		return m, nil
+	}
+	tags, p, err := readString(p)
+	if err != nil {
+		return Message{}, err
+	}
+	if len(tags) == 0 || tags[0] != ',' {
+		return Message{}, ErrInvalidPacket
+	}
+	for _, tag := range tags[1:] {
+		switch tag {
+		case 'i', 'f':
+			if len(p) < 4 {
+				return Message{}, ErrInvalidPacket
+			}
+			v := binary.BigEndian.Uint32(p)
+			if tag == 'i' {
+				m.Args = append(m.Args, int32(v))
+			} else {
+				m.Args = append(m.Args, math.Float32frombits(v))
+			}
+			p = p[4:]
+		case 's':
+			var s string
+			if s, p, err = readString(p); err != nil {
+				return Message{}, err
+			}
+			m.Args = append(m.Args, s)
+		case 'b':
+			if len(p) < 4 {
+				return Message{}, ErrInvalidPacket
+			}
+			n := int(int32(binary.BigEndian.Uint32(p)))
+			p = p[4:]
+			if n < 0 || pad(n) > len(p) {
+				return Message{}, ErrInvalidPacket
+			}
+			m.Args = append(m.Args, append([]byte{}, p[:n]...))
+			p = p[pad(n):]
+		case 'T':
+			m.Args = append(m.Args, true)
+		case 'F':
+			m.Args = append(m.Args, false)
+		default:
+			return Message{}, ErrUnsupported
+		}
+	}
+	return m, nil
+}
+
+// Parses an OSC packet, returning the messages it contains. Bundles are
+// flattened in order and their time tags are ignored, every message is
+// to be handled immediately.
+func ParsePacket(p []byte) ([]Message, error) {
+	if len(p) == 0 || len(p)%4 != 0 {
+		return nil, ErrInvalidPacket
+	}
+	if p[0] == '/' {
+		m, err := parseMessage(p)
+		if err != nil {
+			return nil, err
+		}
+		return []Message{m}, nil
+	}
+	id, p, err := readString(p)
+	if err != nil || id != bundleID || len(p) < 8 {
+		return nil, ErrInvalidPacket
+	}
+	p = p[8:] // time tag
+	messages := []Message{}
+	for len(p) > 0 {
+		if len(p) < 4 {
+			return nil, ErrInvalidPacket
+		}
+		n := int(int32(binary.BigEndian.Uint32(p)))
+		p = p[4:]
+		if n < 0 || n > len(p) {
+			return nil, ErrInvalidPacket
+		}
+		ms, err := ParsePacket(p[:n])
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, ms...)
+		p = p[n:]
+	}
+	return messages, nil
+}
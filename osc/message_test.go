@@ -0,0 +1,153 @@
+package osc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageMarshalBinary(t *testing.T) {
+	tt := []struct {
+		name     string
+		message  Message
+		expected []byte
+		err      error
+	}{
+		{
+			"no arguments",
+			Message{Address: "/lightswarm/690/on"},
+			[]byte("/lightswarm/690/on\x00\x00,\x00\x00\x00"),
+			nil,
+		},
+		{
+			"int and float",
+			Message{Address: "/a", Args: []interface{}{int32(255), float32(0.5)}},
+			[]byte("/a\x00\x00,if\x00\x00\x00\x00\xff\x3f\x00\x00\x00"),
+			nil,
+		},
+		{
+			"string, blob and bools",
+			Message{Address: "/a", Args: []interface{}{"hi", []byte{1}, true, false}},
+			[]byte("/a\x00\x00,sbTF\x00\x00\x00hi\x00\x00\x00\x00\x00\x01\x01\x00\x00\x00"),
+			nil,
+		},
+		{
+			"unsupported",
+			Message{Address: "/a", Args: []interface{}{1}},
+			nil,
+			ErrUnsupported,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := tc.message.MarshalBinary()
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.expected, b)
+		})
+	}
+}
+
+// Builds a bundle containing the packets
+func bundle(packets ...[]byte) []byte {
+	b := []byte("#bundle\x00\x00\x00\x00\x00\x00\x00\x00\x01")
+	for _, p := range packets {
+		n := len(p)
+		b = append(b, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+		b = append(b, p...)
+	}
+	return b
+}
+
+// Returns the encoded message
+func marshal(m Message) []byte {
+	b, err := m.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestParsePacket(t *testing.T) {
+	on := Message{Address: "/lightswarm/690/on"}
+	rgb := Message{Address: "/lightswarm/690/rgb", Args: []interface{}{float32(1), float32(0.5), float32(0)}}
+	all := Message{Address: "/a", Args: []interface{}{int32(-1), "hi", []byte{1, 2, 3, 4, 5}, true, false}}
+	tt := []struct {
+		name     string
+		packet   []byte
+		expected []Message
+		err      error
+	}{
+		{
+			"message",
+			marshal(rgb),
+			[]Message{rgb},
+			nil,
+		},
+		{
+			"all argument types",
+			marshal(all),
+			[]Message{all},
+			nil,
+		},
+		{
+			"no type tags",
+			[]byte("/lightswarm/690/on\x00\x00"),
+			[]Message{on},
+			nil,
+		},
+		{
+			"nested bundles",
+			bundle(marshal(on), bundle(marshal(rgb))),
+			[]Message{on, rgb},
+			nil,
+		},
+		{
+			"empty",
+			nil,
+			nil,
+			ErrInvalidPacket,
+		},
+		{
+			"not padded",
+			[]byte("/a\x00"),
+			nil,
+			ErrInvalidPacket,
+		},
+		{
+			"missing argument",
+			[]byte("/a\x00\x00,i\x00\x00"),
+			nil,
+			ErrInvalidPacket,
+		},
+		{
+			"unknown type",
+			[]byte("/a\x00\x00,d\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"),
+			nil,
+			ErrUnsupported,
+		},
+		{
+			"bundle element too long",
+			append(bundle(marshal(on))[:16], 0, 0, 1, 0),
+			nil,
+			ErrInvalidPacket,
+		},
+		{
+			"not osc",
+			[]byte("hello world!"),
+			nil,
+			ErrInvalidPacket,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			messages, err := ParsePacket(tc.packet)
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.expected, messages)
+		})
+	}
+}
+
+func TestMessageString(t *testing.T) {
+	m := Message{Address: "/lightswarm/690/rgb", Args: []interface{}{float32(1), float32(0.5), int32(0)}}
+	assert.Equal(t, "/lightswarm/690/rgb 1 0.5 0", m.String())
+}
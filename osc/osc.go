@@ -0,0 +1,314 @@
+/*
+Package osc controls LightSwarm LED's with Open Sound Control messages
+received over UDP, as sent by TouchDesigner, QLab and most show control
+software.
+
+	/lightswarm/690/on
+	/lightswarm/690/off
+	/lightswarm/690/toggle
+	/lightswarm/690/level     level
+	/lightswarm/690/fade      level interval step
+	/lightswarm/690/fade-down level interval step
+	/lightswarm/690/rgb       red green blue
+	/lightswarm/690/fade-rgb  red interval step green interval step blue interval step
+
+Groups are addressed by psuedo address under /lightswarm/group/ with the
+same methods. Levels may be sent as integers from 0 to 255 or as floats
+from 0 to 1, the usual range of a fader, so both of these set the same
+colour:
+
+	/lightswarm/690/rgb iii 255 128 0
+	/lightswarm/690/rgb fff 1.0 0.5 0.0
+
+Address pattern wildcards are not supported.
+*/
+package osc
+
+import (
+	"errors"
+	"io"
+	"log"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/thisissoon/lightswarm"
+)
+
+// Default address prefix
+const DefaultPrefix = "/lightswarm"
+
+// Message errors
+var (
+	ErrClosed         = errors.New("osc: closed")
+	ErrUnknownAddress = errors.New("osc: unknown address")
+	ErrArgs           = errors.New("osc: invalid arguments")
+)
+
+// A method on a light, taking a fixed number of arguments
+type method struct {
+	args int
+	run  func(l lightswarm.Light, args []interface{}) (int, []byte, error)
+}
+
+// Available methods
+var methods = map[string]method{
+	"on": {0, func(l lightswarm.Light, args []interface{}) (int, []byte, error) {
+		return l.On()
+	}},
+	"off": {0, func(l lightswarm.Light, args []interface{}) (int, []byte, error) {
+		return l.Off()
+	}},
+	"toggle": {0, func(l lightswarm.Light, args []interface{}) (int, []byte, error) {
+		return l.Toggle()
+	}},
+	"level": {1, func(l lightswarm.Light, args []interface{}) (int, []byte, error) {
+		level, err := toLevel(args[0])
+		if err != nil {
+			return 0, nil, err
+		}
+		return l.SetLevel(level)
+	}},
+	"fade": {3, func(l lightswarm.Light, args []interface{}) (int, []byte, error) {
+		f, err := toFade(args)
+		if err != nil {
+			return 0, nil, err
+		}
+		return l.Fade(f)
+	}},
+	"fade-down": {3, func(l lightswarm.Light, args []interface{}) (int, []byte, error) {
+		f, err := toFade(args)
+		if err != nil {
+			return 0, nil, err
+		}
+		return l.FadeDown(f)
+	}},
+	"rgb": {3, func(l lightswarm.Light, args []interface{}) (int, []byte, error) {
+		rgb := make([]byte, 3)
+		for i, arg := range args {
+			level, err := toLevel(arg)
+			if err != nil {
+				return 0, nil, err
+			}
+			rgb[i] = level
+		}
+		return l.SetRGB(rgb[0], rgb[1], rgb[2])
+	}},
+	"fade-rgb": {9, func(l lightswarm.Light, args []interface{}) (int, []byte, error) {
+		fades := make([]lightswarm.Fade, 3)
+		for i := range fades {
+			f, err := toFade(args[i*3 : i*3+3])
+			if err != nil {
+				return 0, nil, err
+			}
+			fades[i] = f
+		}
+		return l.FadeRGB(fades[0], fades[1], fades[2])
+	}},
+}
+
+// Converts an integer from 0 to 255 or a float from 0 to 1 to a level,
+// floats outside the range are clamped
+func toLevel(arg interface{}) (byte, error) {
+	switch v := arg.(type) {
+	case int32:
+		if v < 0 || v > 255 {
+			return 0, ErrArgs
+		}
+		return byte(v), nil
+	case float32:
+		f := math.Max(0, math.Min(1, float64(v)))
+		return byte(math.Floor(f*255 + 0.5)), nil
+	}
+	return 0, ErrArgs
+}
+
+// Converts an integer, or a float holding a whole number, from 0 to max
+// to an int
+func toInt(arg interface{}, max int) (int, error) {
+	var n float64
+	switch v := arg.(type) {
+	case int32:
+		n = float64(v)
+	case float32:
+		n = float64(v)
+	default:
+		return 0, ErrArgs
+	}
+	if n != math.Trunc(n) || n < 0 || n > float64(max) {
+		return 0, ErrArgs
+	}
+	return int(n), nil
+}
+
+// Builds a Fade from level, interval and step arguments, the interval
+// must be from 0 to 255 and the step from 0 to 127
+func toFade(args []interface{}) (lightswarm.Fade, error) {
+	level, err := toLevel(args[0])
+	if err != nil {
+		return lightswarm.Fade{}, err
+	}
+	interval, err := toInt(args[1], 255)
+	if err != nil {
+		return lightswarm.Fade{}, err
+	}
+	step, err := toInt(args[2], 127)
+	if err != nil {
+		return lightswarm.Fade{}, err
+	}
+	return lightswarm.Fade{
+		Level:    int(level),
+		Interval: interval,
+		Step:     step,
+	}, nil
+}
+
+// Receives OSC messages over UDP and writes the commands to Writer, which
+// should usually be a lightswarm.Bus
+type Server struct {
+	// Exported Fields
+	Writer   io.Writer
+	Prefix   string      // Address prefix, defaults to DefaultPrefix
+	ErrorLog *log.Logger // Logs rejected messages, defaults to the log package
+
+	mu     sync.Mutex
+	conns  map[net.PacketConn]bool
+	closed bool
+}
+
+// Returns the address prefix
+func (s *Server) prefix() string {
+	if s.Prefix == "" {
+		return DefaultPrefix
+	}
+	return s.Prefix
+}
+
+// Logs to ErrorLog or the standard logger
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// Returns the light and method name addressed by the message
+func (s *Server) route(address string) (lightswarm.Light, string, error) {
+	if !strings.HasPrefix(address, s.prefix()+"/") {
+		return nil, "", ErrUnknownAddress
+	}
+	parts := strings.Split(strings.TrimPrefix(address, s.prefix()+"/"), "/")
+	group := len(parts) == 3 && parts[0] == "group"
+	if group {
+		parts = parts[1:]
+	}
+	if len(parts) != 2 {
+		return nil, "", ErrUnknownAddress
+	}
+	addr, err := strconv.ParseUint(parts[0], 10, 16)
+	if err != nil {
+		return nil, "", ErrUnknownAddress
+	}
+	if group {
+		return lightswarm.NewGroup(uint16(addr), s.Writer), parts[1], nil
+	}
+	return lightswarm.New(uint16(addr), s.Writer), parts[1], nil
+}
+
+// Sends the command for a single message
+func (s *Server) Handle(m Message) (int, []byte, error) {
+	l, name, err := s.route(m.Address)
+	if err != nil {
+		return 0, nil, err
+	}
+	method, ok := methods[name]
+	if !ok {
+		return 0, nil, ErrUnknownAddress
+	}
+	if len(m.Args) != method.args {
+		return 0, nil, ErrArgs
+	}
+	return method.run(l, m.Args)
+}
+
+// Parses a packet and handles every message it contains, messages which
+// fail are logged and do not prevent the rest being handled
+func (s *Server) HandlePacket(p []byte) error {
+	messages, err := ParsePacket(p)
+	if err != nil {
+		return err
+	}
+	for _, m := range messages {
+		if _, _, err := s.Handle(m); err != nil {
+			s.logf("osc: %s: %s", m, err)
+		}
+	}
+	return nil
+}
+
+// Reads packets from the connection until it fails or the server is
+// closed, in which case ErrClosed is returned
+func (s *Server) Serve(conn net.PacketConn) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+		return ErrClosed
+	}
+	if s.conns == nil {
+		s.conns = map[net.PacketConn]bool{}
+	}
+	s.conns[conn] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrClosed
+			}
+			return err
+		}
+		if err := s.HandlePacket(buf[:n]); err != nil {
+			s.logf("osc: %s: %s", addr, err)
+		}
+	}
+}
+
+// Listens on the UDP address and serves packets
+func (s *Server) ListenAndServe(addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(conn)
+}
+
+// Closes all connections
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	return nil
+}
+
+// Constructs a new Server writing to the given writer
+func NewServer(writer io.Writer) *Server {
+	return &Server{
+		Writer: writer,
+	}
+}
@@ -0,0 +1,244 @@
+package osc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thisissoon/lightswarm"
+)
+
+// A buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte{}, b.buf.Bytes()...)
+}
+
+func TestServerHandle(t *testing.T) {
+	tt := []struct {
+		name     string
+		message  Message
+		expected lightswarm.Frame
+		err      error
+	}{
+		{
+			"on",
+			Message{Address: "/lightswarm/690/on"},
+			lightswarm.Frame{Addr: 690, Cmd: lightswarm.ON},
+			nil,
+		},
+		{
+			"group toggle",
+			Message{Address: "/lightswarm/group/3/toggle"},
+			lightswarm.Frame{Addr: 3, Cmd: lightswarm.TOGGLE},
+			nil,
+		},
+		{
+			"level float",
+			Message{Address: "/lightswarm/690/level", Args: []interface{}{float32(0.5)}},
+			lightswarm.Frame{Addr: 690, Cmd: lightswarm.SET_LEVEL, CmdArgs: []byte{128}},
+			nil,
+		},
+		{
+			"rgb floats",
+			Message{Address: "/lightswarm/690/rgb", Args: []interface{}{float32(1), float32(0.5), float32(-1)}},
+			lightswarm.Frame{Addr: 690, Cmd: lightswarm.SET_RGB_LEVELS, CmdArgs: []byte{255, 128, 0}},
+			nil,
+		},
+		{
+			"rgb ints",
+			Message{Address: "/lightswarm/690/rgb", Args: []interface{}{int32(85), int32(199), int32(237)}},
+			lightswarm.Frame{Addr: 690, Cmd: lightswarm.SET_RGB_LEVELS, CmdArgs: []byte{85, 199, 237}},
+			nil,
+		},
+		{
+			"group fade",
+			Message{Address: "/lightswarm/group/3/fade", Args: []interface{}{int32(255), int32(1), int32(5)}},
+			lightswarm.Frame{Addr: 3, Cmd: lightswarm.FADE_TO_LEVEL, CmdArgs: []byte{255, 1, 5}},
+			nil,
+		},
+		{
+			"wrong number of arguments",
+			Message{Address: "/lightswarm/690/fade", Args: []interface{}{int32(255)}},
+			lightswarm.Frame{},
+			ErrArgs,
+		},
+		{
+			"level out of range",
+			Message{Address: "/lightswarm/690/level", Args: []interface{}{int32(256)}},
+			lightswarm.Frame{},
+			ErrArgs,
+		},
+		{
+			"fade float interval",
+			Message{Address: "/lightswarm/690/fade", Args: []interface{}{int32(255), float32(2), int32(5)}},
+			lightswarm.Frame{Addr: 690, Cmd: lightswarm.FADE_TO_LEVEL, CmdArgs: []byte{255, 2, 5}},
+			nil,
+		},
+		{
+			"fade negative interval",
+			Message{Address: "/lightswarm/690/fade", Args: []interface{}{int32(255), int32(-1), int32(5)}},
+			lightswarm.Frame{},
+			ErrArgs,
+		},
+		{
+			"fade interval out of range",
+			Message{Address: "/lightswarm/690/fade", Args: []interface{}{int32(255), int32(256), int32(5)}},
+			lightswarm.Frame{},
+			ErrArgs,
+		},
+		{
+			"fade step out of range",
+			Message{Address: "/lightswarm/690/fade-rgb", Args: []interface{}{
+				int32(255), int32(1), int32(1),
+				int32(255), int32(1), int32(1),
+				int32(255), int32(1), int32(128),
+			}},
+			lightswarm.Frame{},
+			ErrArgs,
+		},
+		{
+			"fade fractional step",
+			Message{Address: "/lightswarm/690/fade-down", Args: []interface{}{int32(0), int32(1), float32(2.5)}},
+			lightswarm.Frame{},
+			ErrArgs,
+		},
+		{
+			"string argument",
+			Message{Address: "/lightswarm/690/level", Args: []interface{}{"full"}},
+			lightswarm.Frame{},
+			ErrArgs,
+		},
+		{
+			"unknown method",
+			Message{Address: "/lightswarm/690/explode"},
+			lightswarm.Frame{},
+			ErrUnknownAddress,
+		},
+		{
+			"invalid address",
+			Message{Address: "/lightswarm/65536/on"},
+			lightswarm.Frame{},
+			ErrUnknownAddress,
+		},
+		{
+			"other prefix",
+			Message{Address: "/other/690/on"},
+			lightswarm.Frame{},
+			ErrUnknownAddress,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			buff := bytes.NewBuffer(nil)
+			s := NewServer(buff)
+			_, b, err := s.Handle(tc.message)
+			assert.Equal(t, tc.err, err)
+			if tc.err == nil {
+				assert.Equal(t, tc.expected.Bytes(), b)
+				assert.Equal(t, tc.expected.Bytes(), buff.Bytes())
+			}
+		})
+	}
+}
+
+func TestServerPrefix(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	s := NewServer(buff)
+	s.Prefix = "/stage"
+	_, _, err := s.Handle(Message{Address: "/stage/690/off"})
+	assert.Nil(t, err)
+	assert.Equal(t, lightswarm.Frame{Addr: 690, Cmd: lightswarm.OFF}.Bytes(), buff.Bytes())
+}
+
+func TestServerHandlePacket(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	logs := bytes.NewBuffer(nil)
+	s := NewServer(buff)
+	s.ErrorLog = log.New(logs, "", 0)
+	err := s.HandlePacket(bundle(
+		marshal(Message{Address: "/lightswarm/690/on"}),
+		marshal(Message{Address: "/lightswarm/690/explode"}),
+		marshal(Message{Address: "/lightswarm/227/on"}),
+	))
+	assert.Nil(t, err)
+	expected := append(
+		lightswarm.Frame{Addr: 690, Cmd: lightswarm.ON}.Bytes(),
+		lightswarm.Frame{Addr: 227, Cmd: lightswarm.ON}.Bytes()...)
+	assert.Equal(t, expected, buff.Bytes())
+	assert.Equal(t, "osc: /lightswarm/690/explode: osc: unknown address\n", logs.String())
+	assert.Equal(t, ErrInvalidPacket, s.HandlePacket([]byte("nope")))
+}
+
+func TestServerServe(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buff := &syncBuffer{}
+	s := NewServer(buff)
+	s.ErrorLog = log.New(ioutil.Discard, "", 0)
+	done := make(chan error)
+	go func() { done <- s.Serve(conn) }()
+	sender, err := net.Dial("udp4", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	expected := lightswarm.Frame{Addr: 690, Cmd: lightswarm.ON}.Bytes()
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) && len(buff.Bytes()) == 0 {
+		sender.Write(marshal(Message{Address: "/lightswarm/690/on"}))
+		time.Sleep(time.Millisecond * 5)
+	}
+	s.Close()
+	assert.Equal(t, ErrClosed, <-done)
+	assert.Equal(t, expected, buff.Bytes()[:len(expected)])
+	// serving after close fails
+	conn, err = net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ErrClosed, s.Serve(conn))
+}
+
+func TestServerZeroValue(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buff := &syncBuffer{}
+	s := &Server{Writer: buff, ErrorLog: log.New(ioutil.Discard, "", 0)}
+	done := make(chan error)
+	go func() { done <- s.Serve(conn) }()
+	sender, err := net.Dial("udp4", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) && len(buff.Bytes()) == 0 {
+		sender.Write(marshal(Message{Address: "/lightswarm/690/on"}))
+		time.Sleep(time.Millisecond * 5)
+	}
+	s.Close()
+	assert.Equal(t, ErrClosed, <-done)
+	assert.NotEmpty(t, buff.Bytes())
+}
@@ -0,0 +1,147 @@
+package lightswarm
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Default LightSwarm serial baud rate
+const DefaultBaud = 38400
+
+// Bits on the wire per byte, a start bit, 8 data bits and a stop bit (8N1)
+const bitsPerByte = 10
+
+// Returns the time taken to transmit n bytes at the given baud rate
+func WireTime(n int, baud int) time.Duration {
+	if baud <= 0 {
+		baud = DefaultBaud
+	}
+	return time.Duration(n*bitsPerByte) * time.Second / time.Duration(baud)
+}
+
+// Returns the time taken to transmit the encoded frame, including any
+// escape sequences, at the given baud rate
+func (f Frame) WireTime(baud int) time.Duration {
+	return WireTime(len(f.Bytes()), baud)
+}
+
+// Paces writes so that the bus is never flooded faster than the hardware
+// can receive. Each write waits until the previous one has finished
+// transmitting plus the inter-frame Gap. Setting Rate additionally caps
+// the number of writes per second with a token bucket allowing bursts of
+// up to Burst writes. The zero value paces at DefaultBaud with no gap.
+type Pacer struct {
+	// Exported Fields
+	Writer io.Writer
+	Baud   int
+	Gap    time.Duration
+	Rate   float64 // Maximum writes per second, 0 is unlimited
+	Burst  int     // Token bucket size, defaults to 1 when Rate is set
+
+	once   sync.Once
+	sem    chan struct{} // held while waiting to write
+	ready  time.Time     // time the wire is next free
+	tokens float64
+	last   time.Time // time the tokens were last refilled
+
+	// Overridden in tests
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+// Creates the write semaphore and defaults the clock on first use, so
+// a Pacer built as a literal is usable
+func (p *Pacer) init() {
+	p.once.Do(func() {
+		p.sem = make(chan struct{}, 1)
+		if p.now == nil {
+			p.now = time.Now
+		}
+		if p.sleep == nil {
+			p.sleep = SleepContext
+		}
+	})
+}
+
+// Returns the token bucket size
+func (p *Pacer) burst() float64 {
+	if p.Burst < 1 {
+		return 1
+	}
+	return float64(p.Burst)
+}
+
+// Returns how long to wait for a token at the given time, taking one
+func (p *Pacer) take(now time.Time) time.Duration {
+	if p.Rate <= 0 {
+		return 0
+	}
+	if p.last.IsZero() {
+		p.tokens = p.burst()
+	} else {
+		p.tokens += now.Sub(p.last).Seconds() * p.Rate
+		if p.tokens > p.burst() {
+			p.tokens = p.burst()
+		}
+	}
+	p.last = now
+	p.tokens--
+	if p.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-p.tokens / p.Rate * float64(time.Second))
+}
+
+// Reserves the wire for n bytes, returning how long to wait before writing
+func (p *Pacer) reserve(n int) time.Duration {
+	now := p.now()
+	start := now
+	if p.ready.After(start) {
+		start = p.ready
+	}
+	if wait := p.take(now); now.Add(wait).After(start) {
+		start = now.Add(wait)
+	}
+	p.ready = start.Add(WireTime(n, p.Baud) + p.Gap)
+	return start.Sub(now)
+}
+
+// Waits for the wire to be free then writes to the underlying writer
+func (p *Pacer) Write(b []byte) (int, error) {
+	return p.WriteContext(context.Background(), b)
+}
+
+// Waits for the wire to be free then writes to the underlying writer,
+// the write is dropped if the context is done while waiting
+func (p *Pacer) WriteContext(ctx context.Context, b []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	p.init()
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	defer func() { <-p.sem }()
+	ready, tokens, last := p.ready, p.tokens, p.last
+	if d := p.reserve(len(b)); d > 0 {
+		if err := p.sleep(ctx, d); err != nil {
+			// release the reservation
+			p.ready, p.tokens, p.last = ready, tokens, last
+			return 0, err
+		}
+	}
+	return WriteContext(ctx, p.Writer, b)
+}
+
+// Constructs a new Pacer for the given baud rate and inter-frame gap
+func NewPacer(writer io.Writer, baud int, gap time.Duration) *Pacer {
+	return &Pacer{
+		Writer: writer,
+		Baud:   baud,
+		Gap:    gap,
+	}
+}
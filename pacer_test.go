@@ -0,0 +1,178 @@
+package lightswarm
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A fake clock where sleeping advances time
+type fakeClock struct {
+	t     time.Time
+	slept []time.Duration
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.slept = append(c.slept, d)
+	c.t = c.t.Add(d)
+	return nil
+}
+
+// Constructs a Pacer using a fake clock
+func fakePacer(baud int, gap time.Duration) (*Pacer, *fakeClock, *bytes.Buffer) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	buff := bytes.NewBuffer(nil)
+	p := NewPacer(buff, baud, gap)
+	p.now = clock.now
+	p.sleep = clock.sleep
+	return p, clock, buff
+}
+
+func TestWireTime(t *testing.T) {
+	tt := []struct {
+		name     string
+		n        int
+		baud     int
+		expected time.Duration
+	}{
+		{"no bytes", 0, 38400, 0},
+		{"one byte at 38400", 1, 38400, 260416 * time.Nanosecond},
+		{"96 bytes at 9600", 96, 9600, 100 * time.Millisecond},
+		{"default baud", 3840, 0, time.Second},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, WireTime(tc.n, tc.baud))
+		})
+	}
+}
+
+func TestFrameWireTime(t *testing.T) {
+	tt := []struct {
+		name     string
+		frame    Frame
+		expected time.Duration
+	}{
+		{
+			"turn 690 on",
+			Frame{690, ON, nil},
+			WireTime(6, DefaultBaud),
+		},
+		{
+			"turn 738 on includes escaped checksum",
+			Frame{738, ON, nil},
+			WireTime(7, DefaultBaud),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.frame.WireTime(DefaultBaud))
+		})
+	}
+}
+
+func TestPacerWrite(t *testing.T) {
+	frame := []byte{END, 2, 178, ON, 144, END}
+	tt := []struct {
+		name     string
+		baud     int
+		gap      time.Duration
+		rate     float64
+		burst    int
+		writes   int
+		expected []time.Duration
+	}{
+		{
+			"single write does not wait",
+			9600, 0, 0, 0,
+			1,
+			nil,
+		},
+		{
+			"back to back writes wait for the wire",
+			9600, 0, 0, 0,
+			3,
+			[]time.Duration{6250 * time.Microsecond, 6250 * time.Microsecond},
+		},
+		{
+			"inter-frame gap",
+			9600, time.Millisecond, 0, 0,
+			2,
+			[]time.Duration{7250 * time.Microsecond},
+		},
+		{
+			"rate limit",
+			9600, 0, 10, 0,
+			3,
+			[]time.Duration{100 * time.Millisecond, 100 * time.Millisecond},
+		},
+		{
+			"rate limit burst",
+			9600, 0, 10, 2,
+			3,
+			[]time.Duration{6250 * time.Microsecond, 93750 * time.Microsecond},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p, clock, buff := fakePacer(tc.baud, tc.gap)
+			p.Rate = tc.rate
+			p.Burst = tc.burst
+			for i := 0; i < tc.writes; i++ {
+				n, err := p.Write(frame)
+				assert.Nil(t, err)
+				assert.Equal(t, len(frame), n)
+			}
+			assert.Equal(t, tc.expected, clock.slept)
+			assert.Equal(t, tc.writes*len(frame), buff.Len())
+		})
+	}
+}
+
+func TestPacerWriteIdle(t *testing.T) {
+	p, clock, _ := fakePacer(9600, 0)
+	p.Write([]byte{END, 2, 178, ON, 144, END})
+	clock.t = clock.t.Add(time.Second)
+	p.Write([]byte{END, 2, 178, ON, 144, END})
+	assert.Nil(t, clock.slept)
+}
+
+func TestPacerWriteContext(t *testing.T) {
+	frame := []byte{END, 2, 178, ON, 144, END}
+	p, clock, buff := fakePacer(9600, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := p.WriteContext(ctx, frame)
+	assert.Nil(t, err)
+	cancel()
+	// the second write must wait for the wire so is dropped
+	n, err := p.WriteContext(ctx, frame)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, len(frame), buff.Len())
+	// the dropped write does not hold the wire
+	_, err = p.Write(frame)
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Duration{6250 * time.Microsecond}, clock.slept)
+}
+
+func TestPacerZeroValue(t *testing.T) {
+	frame := []byte{END, 2, 178, ON, 144, END}
+	buff := bytes.NewBuffer(nil)
+	p := &Pacer{Writer: buff}
+	for i := 0; i < 2; i++ {
+		n, err := p.Write(frame)
+		assert.Nil(t, err)
+		assert.Equal(t, len(frame), n)
+	}
+	assert.Equal(t, 2*len(frame), buff.Len())
+}
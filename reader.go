@@ -0,0 +1,126 @@
+package lightswarm
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Returned by Reader.ReadFrame when a single frame on the stream could
+// not be decoded, the stream itself remains usable
+type FrameError struct {
+	Raw []byte // The undecoded frame bytes, without END delimiters
+	Err error  // The decoding error
+}
+
+func (e *FrameError) Error() string {
+	return fmt.Sprintf("%s: % x", e.Err, e.Raw)
+}
+
+// A bufio.SplitFunc that splits a byte stream into END delimited frames.
+// Returned tokens have their END bytes removed, empty frames caused by
+// back to back END bytes are skipped. Any bytes preceding the first END
+// byte are returned as a token of their own so that garbage or a partial
+// frame at the start of a stream fails to decode rather than corrupting
+// the following frame.
+func ScanFrames(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	// skip any END bytes until the start of the next frame
+	start := 0
+	for start < len(data) && data[start] == END {
+		start++
+	}
+	if i := bytes.IndexByte(data[start:], END); i >= 0 {
+		return start + i + 1, data[start : start+i], nil
+	}
+	if atEOF && start < len(data) {
+		return len(data), data[start:], nil
+	}
+	// request more data, dropping any END bytes already consumed
+	return start, nil, nil
+}
+
+// Maximum number of bytes Reader buffers while waiting for the END byte
+// closing a frame, a MaxFrameLen frame with every byte escaped
+const maxScanLen = 2 * MaxFrameLen
+
+// Reads successive frames from an io.Reader such as a serial port
+type Reader struct {
+	scanner *bufio.Scanner
+	discard bool // dropping the rest of an overlong frame
+	tooLong bool // the last token is the start of an overlong frame
+}
+
+// Splits frames as ScanFrames, but once maxScanLen bytes are buffered
+// without an END byte they are returned as an overlong token and the
+// rest of the frame is dropped up to the next END byte, so the scanner
+// never stops with bufio.ErrTooLong
+func (r *Reader) split(data []byte, atEOF bool) (int, []byte, error) {
+	start := 0
+	if r.discard {
+		i := bytes.IndexByte(data, END)
+		if i < 0 {
+			return len(data), nil, nil
+		}
+		r.discard = false
+		start = i
+	}
+	advance, token, err := ScanFrames(data[start:], atEOF)
+	if token == nil && err == nil && len(data)-start-advance >= maxScanLen {
+		r.discard, r.tooLong = true, true
+		return len(data), data[start+advance:], nil
+	}
+	return start + advance, token, err
+}
+
+// Reads the bytes of the next frame without decoding them, still escaped
+// and without END delimiters, for callers such as sniffers which describe
+// frames themselves. Frames longer than the Reader buffers fail with a
+// *FrameError holding ErrFrameTooLong and their first bytes. The bytes
+// are only valid until the next read.
+func (r *Reader) ReadRaw() ([]byte, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	raw := r.scanner.Bytes()
+	if r.tooLong {
+		r.tooLong = false
+		return nil, &FrameError{
+			Raw: append([]byte{}, raw...),
+			Err: ErrFrameTooLong,
+		}
+	}
+	return raw, nil
+}
+
+// Reads the next frame from the stream. Decoding errors are returned as
+// a *FrameError and do not stop the stream, io.EOF is returned once the
+// underlying reader is exhausted. Frames longer than the Reader buffers
+// fail with ErrFrameTooLong, the error holding only their first bytes.
+func (r *Reader) ReadFrame() (Frame, error) {
+	raw, err := r.ReadRaw()
+	if err != nil {
+		return Frame{}, err
+	}
+	f, err := ParseFrame(raw)
+	if err != nil {
+		return Frame{}, &FrameError{
+			Raw: append([]byte{}, raw...),
+			Err: err,
+		}
+	}
+	return f, nil
+}
+
+// Constructs a new Reader
+func NewReader(r io.Reader) *Reader {
+	reader := &Reader{
+		scanner: bufio.NewScanner(r),
+	}
+	reader.scanner.Buffer(make([]byte, maxScanLen), maxScanLen)
+	reader.scanner.Split(reader.split)
+	return reader
+}
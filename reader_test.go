@@ -0,0 +1,167 @@
+package lightswarm
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A reader that always fails
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+func TestFrameError(t *testing.T) {
+	err := &FrameError{Raw: []byte{2, 178}, Err: ErrTruncated}
+	assert.Equal(t, "lightswarm: truncated frame: 02 b2", err.Error())
+}
+
+func TestScanFrames(t *testing.T) {
+	tt := []struct {
+		name     string
+		bs       []byte
+		expected [][]byte
+	}{
+		{
+			"single frame",
+			[]byte{END, 2, 178, ON, 144, END},
+			[][]byte{{2, 178, ON, 144}},
+		},
+		{
+			"back to back end bytes",
+			[]byte{END, END, END, 2, 178, ON, 144, END, END, END},
+			[][]byte{{2, 178, ON, 144}},
+		},
+		{
+			"multiple frames",
+			[]byte{END, 2, 178, ON, 144, END, END, 2, 178, OFF, 145, END},
+			[][]byte{{2, 178, ON, 144}, {2, 178, OFF, 145}},
+		},
+		{
+			"leading garbage",
+			[]byte{1, 2, 3, END, 2, 178, ON, 144, END},
+			[][]byte{{1, 2, 3}, {2, 178, ON, 144}},
+		},
+		{
+			"unterminated frame at eof",
+			[]byte{END, 2, 178, ON, 144, END, 2, 178},
+			[][]byte{{2, 178, ON, 144}, {2, 178}},
+		},
+		{
+			"only end bytes",
+			[]byte{END, END},
+			nil,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// read a byte at a time to exercise partial frames
+			scanner := bufio.NewScanner(iotest.OneByteReader(bytes.NewReader(tc.bs)))
+			scanner.Split(ScanFrames)
+			var tokens [][]byte
+			for scanner.Scan() {
+				tokens = append(tokens, append([]byte{}, scanner.Bytes()...))
+			}
+			assert.Nil(t, scanner.Err())
+			assert.Equal(t, tc.expected, tokens)
+		})
+	}
+}
+
+func TestReaderReadFrame(t *testing.T) {
+	type result struct {
+		frame Frame
+		err   error
+	}
+	tt := []struct {
+		name     string
+		bs       []byte
+		expected []result
+	}{
+		{
+			"turn 690 on then off",
+			[]byte{END, 2, 178, ON, 144, END, END, 2, 178, OFF, 145, END},
+			[]result{
+				{Frame{690, ON, nil}, nil},
+				{Frame{690, OFF, nil}, nil},
+			},
+		},
+		{
+			"resynchronise after garbage",
+			[]byte{0xFF, 0x01, END, 2, 178, ON, 144, END},
+			[]result{
+				{Frame{}, &FrameError{[]byte{0xFF, 0x01}, ErrTruncated}},
+				{Frame{690, ON, nil}, nil},
+			},
+		},
+		{
+			"bad checksum does not stop the stream",
+			[]byte{END, 2, 178, ON, 145, END, END, 2, 178, OFF, 145, END},
+			[]result{
+				{Frame{}, &FrameError{[]byte{2, 178, ON, 145}, ErrChecksum}},
+				{Frame{690, OFF, nil}, nil},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(tc.bs))
+			for _, expected := range tc.expected {
+				f, err := r.ReadFrame()
+				assert.Equal(t, expected.err, err)
+				assert.Equal(t, expected.frame, f)
+			}
+			_, err := r.ReadFrame()
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestReaderReadFrameError(t *testing.T) {
+	boom := errors.New("boom")
+	r := NewReader(errReader{boom})
+	_, err := r.ReadFrame()
+	assert.Equal(t, boom, err)
+}
+
+func TestReaderFrameTooLong(t *testing.T) {
+	garbage := bytes.Repeat([]byte{0xFF}, 10000)
+	bs := append([]byte{END}, garbage...)
+	bs = append(bs, END, 2, 178, ON, 144, END)
+	r := NewReader(bytes.NewReader(bs))
+	_, err := r.ReadFrame()
+	assert.Equal(t, &FrameError{garbage[:maxScanLen], ErrFrameTooLong}, err)
+	// the rest of the garbage is dropped and the stream continues
+	f, err := r.ReadFrame()
+	assert.Nil(t, err)
+	assert.Equal(t, Frame{690, ON, nil}, f)
+	_, err = r.ReadFrame()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReaderReadRaw(t *testing.T) {
+	garbage := bytes.Repeat([]byte{0xFF}, 10000)
+	bs := []byte{END, 2, 178, ON, 144, END}
+	bs = append(bs, garbage...)
+	bs = append(bs, END, 2, 178, OFF, 145, END)
+	r := NewReader(bytes.NewReader(bs))
+	raw, err := r.ReadRaw()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{2, 178, ON, 144}, raw)
+	_, err = r.ReadRaw()
+	assert.Equal(t, &FrameError{garbage[:maxScanLen], ErrFrameTooLong}, err)
+	raw, err = r.ReadRaw()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{2, 178, OFF, 145}, raw)
+	_, err = r.ReadRaw()
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,230 @@
+package lightswarm
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// The state an address is moved to by a scene, unset fields are left
+// unchanged
+type Target struct {
+	On    *bool  `json:"on,omitempty" yaml:"on,omitempty"`
+	Level *byte  `json:"level,omitempty" yaml:"level,omitempty"`
+	RGB   *Color `json:"rgb,omitempty" yaml:"rgb,omitempty"`
+}
+
+// A named snapshot of many addresses. Targets may use psuedo addresses to
+// move a whole group, targets for members of the group override it.
+type Scene struct {
+	Name    string            `json:"name" yaml:"name"`
+	Targets map[uint16]Target `json:"targets" yaml:"targets"`
+}
+
+// Returns the current state of the address as a Target, fields are nil
+// when unknown. Fades in progress are treated as complete.
+func (t *Tracker) target(addr uint16, now time.Time) Target {
+	s, ok := t.states[addr]
+	if !ok {
+		return Target{}
+	}
+	state := s.state(now)
+	on, level, rgb := state.On, state.Level, Color{state.RGB[0], state.RGB[1], state.RGB[2]}
+	if state.Fade != nil {
+		level = state.Fade.Level
+	}
+	channels := []*byte{&rgb.R, &rgb.G, &rgb.B}
+	for i, f := range state.RGBFade {
+		if f != nil {
+			*channels[i] = f.Level
+		}
+	}
+	return Target{On: &on, Level: &level, RGB: &rgb}
+}
+
+// Returns the fields shared by both targets, fields which differ are nil
+func intersect(a, b Target) Target {
+	if a.On == nil || b.On == nil || *a.On != *b.On {
+		a.On = nil
+	}
+	if a.Level == nil || b.Level == nil || *a.Level != *b.Level {
+		a.Level = nil
+	}
+	if a.RGB == nil || b.RGB == nil || *a.RGB != *b.RGB {
+		a.RGB = nil
+	}
+	return a
+}
+
+// Returns the target with the set fields of o applied
+func (target Target) apply(o Target) Target {
+	if o.On != nil {
+		target.On = o.On
+	}
+	if o.Level != nil {
+		target.Level = o.Level
+	}
+	if o.RGB != nil {
+		target.RGB = o.RGB
+	}
+	return target
+}
+
+// Returns the target with only the fields also set in o
+func (target Target) only(o Target) Target {
+	if o.On == nil {
+		target.On = nil
+	}
+	if o.Level == nil {
+		target.Level = nil
+	}
+	if o.RGB == nil {
+		target.RGB = nil
+	}
+	return target
+}
+
+// Returns the frames moving the tracked state to the scene
+func (t *Tracker) recallFrames(scene Scene, transition time.Duration) []Frame {
+	t.init()
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// groups first so member targets override them
+	addrs := []uint16{}
+	for addr := range scene.Targets {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		gi, gj := len(t.members[addrs[i]]) > 0, len(t.members[addrs[j]]) > 0
+		if gi != gj {
+			return gi
+		}
+		return addrs[i] < addrs[j]
+	})
+	current := map[uint16]Target{}
+	get := func(addr uint16) Target {
+		if c, ok := current[addr]; ok {
+			return c
+		}
+		c := t.target(addr, now)
+		current[addr] = c
+		return c
+	}
+	power, levels, rgbs := []Frame{}, []Frame{}, []Frame{}
+	fades := []AddressFade{}
+	for _, addr := range addrs {
+		want := scene.Targets[addr]
+		// a group is only at a state if every member is, ignoring fields
+		// members override with their own targets
+		members := []uint16{addr}
+		for member := range t.members[addr] {
+			members = append(members, member)
+		}
+		cur := get(addr)
+		for i, member := range members[1:] {
+			m := get(member).apply(want.only(scene.Targets[member]))
+			if i == 0 {
+				cur = m
+			} else {
+				cur = intersect(cur, m)
+			}
+		}
+		sent := Target{}
+		if want.On != nil && (cur.On == nil || *cur.On != *want.On) {
+			sent.On = want.On
+			cmd := OFF
+			if *want.On {
+				cmd = ON
+			}
+			power = append(power, Frame{Addr: addr, Cmd: cmd})
+		}
+		if want.Level != nil && (cur.Level == nil || *cur.Level != *want.Level) {
+			sent.Level = want.Level
+			var from byte
+			if cur.Level != nil {
+				from = *cur.Level
+			}
+			if transition > 0 {
+				fades = append(fades, AddressFade{Addr: addr, Fade: FadeNearest(from, *want.Level, transition)})
+			} else {
+				levels = append(levels, Frame{Addr: addr, Cmd: SET_LEVEL, CmdArgs: []byte{*want.Level}})
+			}
+		}
+		if want.RGB != nil && (cur.RGB == nil || *cur.RGB != *want.RGB) {
+			sent.RGB = want.RGB
+			var from Color
+			if cur.RGB != nil {
+				from = *cur.RGB
+			}
+			if transition > 0 {
+				args := []byte{}
+				args = append(args, FadeNearest(from.R, want.RGB.R, transition).Args()...)
+				args = append(args, FadeNearest(from.G, want.RGB.G, transition).Args()...)
+				args = append(args, FadeNearest(from.B, want.RGB.B, transition).Args()...)
+				rgbs = append(rgbs, Frame{Addr: addr, Cmd: FADE_RGB_TO_LEVEL, CmdArgs: args})
+			} else {
+				rgbs = append(rgbs, Frame{Addr: addr, Cmd: SET_RGB_LEVELS, CmdArgs: []byte{want.RGB.R, want.RGB.G, want.RGB.B}})
+			}
+		}
+		for _, member := range members {
+			current[member] = get(member).apply(sent)
+		}
+	}
+	frames := append(power, levels...)
+	if len(fades) > 1 && t.AllCall != nil {
+		// transition fades are always in range
+		multiple, _ := FadeMultipleFrames(*t.AllCall, fades...)
+		frames = append(frames, multiple...)
+	} else {
+		for _, af := range fades {
+			frames = append(frames, Frame{Addr: af.Addr, Cmd: FADE_TO_LEVEL, CmdArgs: af.Fade.Args()})
+		}
+	}
+	return append(frames, rgbs...)
+}
+
+// Moves the addresses in the scene from their tracked state to the scene
+// over the transition, writing only the frames needed in a single write.
+// Power changes are immediate, level fades for many addresses are batched
+// into FADE_MULTIPLE_TO_LEVEL frames sent to the AllCall address, or sent
+// one FADE_TO_LEVEL frame per address when it is nil. Every field is sent
+// to addresses with no tracked state, fading from 0.
+func (t *Tracker) Recall(scene Scene, transition time.Duration) (int, []byte, error) {
+	return t.RecallContext(context.Background(), scene, transition)
+}
+
+// As Recall but abandoned if the context is done before it is written
+func (t *Tracker) RecallContext(ctx context.Context, scene Scene, transition time.Duration) (int, []byte, error) {
+	b := []byte{}
+	for _, frame := range t.recallFrames(scene, transition) {
+		b = append(b, frame.Bytes()...)
+	}
+	if len(b) == 0 {
+		return 0, nil, nil
+	}
+	n, err := t.WriteContext(ctx, b)
+	if err != nil {
+		return 0, nil, err
+	}
+	return n, b, nil
+}
+
+// Returns a scene holding the tracked state of the addresses, or of every
+// tracked address if none are given
+func (t *Tracker) Snapshot(name string, addrs ...uint16) Scene {
+	if len(addrs) == 0 {
+		addrs = t.Addrs()
+	}
+	t.init()
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	scene := Scene{Name: name, Targets: map[uint16]Target{}}
+	for _, addr := range addrs {
+		if _, ok := t.states[addr]; ok {
+			scene.Targets[addr] = t.target(addr, now)
+		}
+	}
+	return scene
+}
@@ -0,0 +1,222 @@
+package lightswarm
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a pointer to the value
+func boolPtr(b bool) *bool       { return &b }
+func bytePtr(b byte) *byte       { return &b }
+func uint16Ptr(u uint16) *uint16 { return &u }
+
+// Returns the encoded frames
+func frameBytes(frames ...Frame) []byte {
+	b := []byte{}
+	for _, f := range frames {
+		b = append(b, f.Bytes()...)
+	}
+	return b
+}
+
+// Returns the FadeOver fade, failing the test on error
+func fadeOver(t *testing.T, from, to byte, d time.Duration) Fade {
+	f, _, err := FadeOver(from, to, d)
+	assert.Nil(t, err)
+	return f
+}
+
+// Constructs a tracker with 690 and 227 on at level 100 and red, both
+// members of 4096
+func sceneTracker() *Tracker {
+	tracker, _, _ := fakeTracker()
+	for _, addr := range []uint16{690, 227} {
+		led := New(addr, tracker)
+		led.SetPseudoAddress(4096)
+		led.On()
+		led.SetLevel(100)
+		led.SetRGB(255, 0, 0)
+	}
+	return tracker
+}
+
+func TestTrackerRecall(t *testing.T) {
+	red := Color{255, 0, 0}
+	blue := Color{0, 0, 255}
+	tt := []struct {
+		name       string
+		scene      Scene
+		transition time.Duration
+		allCall    *uint16
+		expected   []byte
+	}{
+		{
+			"already at scene",
+			Scene{Targets: map[uint16]Target{
+				690: {On: boolPtr(true), Level: bytePtr(100), RGB: &red},
+			}},
+			time.Second,
+			nil,
+			nil,
+		},
+		{
+			"set levels",
+			Scene{Targets: map[uint16]Target{
+				690: {Level: bytePtr(200)},
+				227: {Level: bytePtr(100), On: boolPtr(false)},
+			}},
+			0,
+			nil,
+			frameBytes(
+				Frame{Addr: 227, Cmd: OFF},
+				Frame{Addr: 690, Cmd: SET_LEVEL, CmdArgs: []byte{200}},
+			),
+		},
+		{
+			"single fade",
+			Scene{Targets: map[uint16]Target{
+				690: {Level: bytePtr(200)},
+			}},
+			time.Second,
+			nil,
+			frameBytes(
+				Frame{Addr: 690, Cmd: FADE_TO_LEVEL, CmdArgs: fadeOver(t, 100, 200, time.Second).Args()},
+			),
+		},
+		{
+			"fade multiple",
+			Scene{Targets: map[uint16]Target{
+				690: {Level: bytePtr(200)},
+				227: {Level: bytePtr(0)},
+			}},
+			time.Second,
+			uint16Ptr(4095),
+			frameBytes(multipleFrames(t, 4095,
+				AddressFade{Addr: 227, Fade: fadeOver(t, 100, 0, time.Second)},
+				AddressFade{Addr: 690, Fade: fadeOver(t, 100, 200, time.Second)},
+			)...),
+		},
+		{
+			"fade multiple without all call address",
+			Scene{Targets: map[uint16]Target{
+				690: {Level: bytePtr(200)},
+				227: {Level: bytePtr(0)},
+			}},
+			time.Second,
+			nil,
+			frameBytes(
+				Frame{Addr: 227, Cmd: FADE_TO_LEVEL, CmdArgs: fadeOver(t, 100, 0, time.Second).Args()},
+				Frame{Addr: 690, Cmd: FADE_TO_LEVEL, CmdArgs: fadeOver(t, 100, 200, time.Second).Args()},
+			),
+		},
+		{
+			"fade rgb",
+			Scene{Targets: map[uint16]Target{
+				690: {RGB: &blue},
+			}},
+			time.Second,
+			nil,
+			frameBytes(Frame{Addr: 690, Cmd: FADE_RGB_TO_LEVEL, CmdArgs: append(append(
+				fadeOver(t, 255, 0, time.Second).Args(),
+				Fade{0, 1, 1}.Args()...),
+				fadeOver(t, 0, 255, time.Second).Args()...),
+			}),
+		},
+		{
+			"group with member override",
+			Scene{Targets: map[uint16]Target{
+				4096: {RGB: &blue},
+				690:  {RGB: &red},
+			}},
+			0,
+			nil,
+			frameBytes(
+				Frame{Addr: 4096, Cmd: SET_RGB_LEVELS, CmdArgs: []byte{0, 0, 255}},
+				Frame{Addr: 690, Cmd: SET_RGB_LEVELS, CmdArgs: []byte{255, 0, 0}},
+			),
+		},
+		{
+			"group already at scene",
+			Scene{Targets: map[uint16]Target{
+				4096: {Level: bytePtr(100)},
+			}},
+			0,
+			nil,
+			nil,
+		},
+		{
+			"unknown address",
+			Scene{Targets: map[uint16]Target{
+				1: {On: boolPtr(true), Level: bytePtr(0), RGB: &blue},
+			}},
+			0,
+			nil,
+			frameBytes(
+				Frame{Addr: 1, Cmd: ON},
+				Frame{Addr: 1, Cmd: SET_LEVEL, CmdArgs: []byte{0}},
+				Frame{Addr: 1, Cmd: SET_RGB_LEVELS, CmdArgs: []byte{0, 0, 255}},
+			),
+		},
+		{
+			"change too small for transition",
+			Scene{Targets: map[uint16]Target{
+				690: {Level: bytePtr(101)},
+			}},
+			time.Second * 10,
+			nil,
+			frameBytes(
+				Frame{Addr: 690, Cmd: FADE_TO_LEVEL, CmdArgs: []byte{101, 255, 1}},
+			),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := sceneTracker()
+			tracker.AllCall = tc.allCall
+			n, b, err := tracker.Recall(tc.scene, tc.transition)
+			assert.Nil(t, err)
+			assert.Equal(t, len(tc.expected), n)
+			assert.Equal(t, tc.expected, b)
+			// recalling again once the fades complete sends nothing
+			tracker.now = func() time.Time { return time.Unix(0, 0).Add(time.Hour) }
+			n, _, err = tracker.Recall(tc.scene, tc.transition)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, n)
+		})
+	}
+}
+
+func TestTrackerSnapshot(t *testing.T) {
+	tracker := sceneTracker()
+	scene := tracker.Snapshot("Opening", 690, 1)
+	assert.Equal(t, Scene{
+		Name: "Opening",
+		Targets: map[uint16]Target{
+			690: {On: boolPtr(true), Level: bytePtr(100), RGB: &Color{255, 0, 0}},
+		},
+	}, scene)
+	assert.Len(t, tracker.Snapshot("All").Targets, 2)
+	// a snapshot recalls to nothing
+	n, _, err := tracker.Recall(scene, time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestSceneJSON(t *testing.T) {
+	data := `{"name":"Dinner","targets":{"690":{"on":true,"level":50,"rgb":"#ff8800"},"4096":{"on":false}}}`
+	var scene Scene
+	assert.Nil(t, json.Unmarshal([]byte(data), &scene))
+	assert.Equal(t, Scene{
+		Name: "Dinner",
+		Targets: map[uint16]Target{
+			690:  {On: boolPtr(true), Level: bytePtr(50), RGB: &Color{255, 136, 0}},
+			4096: {On: boolPtr(false)},
+		},
+	}, scene)
+	b, err := json.Marshal(scene)
+	assert.Nil(t, err)
+	assert.JSONEq(t, data, string(b))
+}
@@ -0,0 +1,78 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"@fortnightly",
+	} {
+		_, err := ParseCron(expr)
+		assert.Equal(t, ErrInvalidCron, err, "%q", expr)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// a Wednesday
+	from := time.Date(2024, 1, 10, 12, 30, 45, 0, time.UTC)
+	tt := []struct {
+		name     string
+		expr     string
+		from     time.Time
+		expected time.Time
+	}{
+		{"every minute", "* * * * *", from, time.Date(2024, 1, 10, 12, 31, 0, 0, time.UTC)},
+		{"daily", "@daily", from, time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)},
+		{"hourly", "@hourly", from, time.Date(2024, 1, 10, 13, 0, 0, 0, time.UTC)},
+		{"later today", "0 18 * * *", from, time.Date(2024, 1, 10, 18, 0, 0, 0, time.UTC)},
+		{"tomorrow", "15 7 * * *", from, time.Date(2024, 1, 11, 7, 15, 0, 0, time.UTC)},
+		{"list", "0 9,17 * * *", from, time.Date(2024, 1, 10, 17, 0, 0, 0, time.UTC)},
+		{"range and step", "*/20 13-14 * * *", from, time.Date(2024, 1, 10, 13, 0, 0, 0, time.UTC)},
+		{"start and step", "10/20 * * * *", from, time.Date(2024, 1, 10, 12, 50, 0, 0, time.UTC)},
+		{"weekday name", "0 8 * * mon-fri", time.Date(2024, 1, 12, 9, 0, 0, 0, time.UTC), time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 8 * * 7", from, time.Date(2024, 1, 14, 8, 0, 0, 0, time.UTC)},
+		{"month name", "0 0 1 jun *", from, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"day of month or week", "0 0 13 * fri", from, time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC)},
+		{"exact minute is after", "30 12 * * *", time.Date(2024, 1, 10, 12, 30, 0, 0, time.UTC), time.Date(2024, 1, 11, 12, 30, 0, 0, time.UTC)},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := ParseCron(tc.expr)
+			assert.Nil(t, err)
+			next, ok := c.Next(tc.from)
+			assert.True(t, ok)
+			assert.Equal(t, tc.expected, next)
+		})
+	}
+}
+
+func TestCronNextNever(t *testing.T) {
+	c, err := ParseCron("0 0 31 2 *")
+	assert.Nil(t, err)
+	_, ok := c.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.False(t, ok)
+}
+
+func TestCronNextLocation(t *testing.T) {
+	bst := time.FixedZone("BST", 3600)
+	c, _ := ParseCron("0 7 * * *")
+	next, _ := c.Next(time.Date(2024, 6, 1, 5, 0, 0, 0, time.UTC).In(bst))
+	assert.True(t, time.Date(2024, 6, 1, 6, 0, 0, 0, time.UTC).Equal(next))
+}
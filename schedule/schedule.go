@@ -0,0 +1,482 @@
+/*
+Package schedule runs scene recalls and LED commands at times given by cron
+expressions or by sunrise, sunset and civil twilight, calculated locally
+from the venue latitude and longitude.
+
+	s := schedule.NewScheduler(tracker, 51.5074, -0.1278)
+	s.Scenes = map[string]lightswarm.Scene{"evening": evening}
+	s.Path = "/var/lib/lightswarm/jobs.json"
+	s.Load()
+	s.Add(schedule.Job{
+		Name:    "facade-on",
+		When:    "dusk-15m",
+		CatchUp: schedule.CatchUpLast,
+		Action:  schedule.Action{Scene: "evening", Transition: schedule.Duration(time.Minute)},
+	})
+	s.Add(schedule.Job{
+		Name:   "facade-off",
+		When:   "30 1 * * *",
+		Action: schedule.Action{Addr: 4096, Group: true, Command: "off"},
+	})
+	log.Fatal(s.Run(ctx))
+
+The job list is saved to Path whenever it changes or jobs run, recording
+how far each job has been checked. When the scheduler restarts, jobs with
+the CatchUpLast policy run the latest occurrence missed while it was
+stopped, in the order they were missed, so a light switched on at dusk
+and off at dawn ends up in the right state.
+*/
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thisissoon/lightswarm"
+)
+
+// Job errors
+var (
+	ErrInvalidAction = errors.New("schedule: invalid action")
+	ErrUnknownScene  = errors.New("schedule: unknown scene")
+	ErrUnknownJob    = errors.New("schedule: unknown job")
+	ErrNoJobName     = errors.New("schedule: job has no name")
+)
+
+// Returns the next time a job should run after the given time
+type Trigger interface {
+	Next(after time.Time) (time.Time, bool)
+}
+
+// A time.Duration encoded as a string such as "1m30s"
+type Duration time.Duration
+
+// Returns the duration string
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Parses a duration string
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// What to do about occurrences missed while the scheduler was stopped
+type CatchUp string
+
+// Catch up policies
+const (
+	CatchUpNone CatchUp = ""     // Missed occurrences are skipped
+	CatchUpLast CatchUp = "last" // The latest missed occurrence is run
+)
+
+// What a job does, either recall a scene or send a command
+type Action struct {
+	Scene      string   `json:"scene,omitempty"`      // Name of the scene to recall
+	Transition Duration `json:"transition,omitempty"` // Scene transition
+	Addr       uint16   `json:"addr,omitempty"`
+	Group      bool     `json:"group,omitempty"`   // Addr is a psuedo address
+	Command    string   `json:"command,omitempty"` // on, off, toggle, level, fade, rgb or fade-rgb
+	Args       []int    `json:"args,omitempty"`    // Command arguments as for lightswarmctl
+}
+
+// Returns the named command with its arguments decoded
+func (a Action) command() (lightswarm.Command, error) {
+	named, ok := lightswarm.LookupCommand(a.Command)
+	if !ok || len(a.Args) != len(named.Args) {
+		return nil, ErrInvalidAction
+	}
+	args := make([]byte, len(a.Args))
+	for i, arg := range a.Args {
+		if arg < 0 || arg > 255 {
+			return nil, ErrInvalidAction
+		}
+		args[i] = byte(arg)
+	}
+	cmd, err := named.Command(args)
+	if err != nil {
+		return nil, ErrInvalidAction
+	}
+	return cmd, nil
+}
+
+// A scheduled action
+type Job struct {
+	Name    string    `json:"name"`
+	When    string    `json:"when"` // Cron expression or sun event such as "dusk-30m"
+	CatchUp CatchUp   `json:"catch_up,omitempty"`
+	Action  Action    `json:"action"`
+	Checked time.Time `json:"checked"` // Occurrences up to this time have been run or skipped
+}
+
+// A job and its parsed trigger
+type job struct {
+	Job
+	trigger Trigger
+}
+
+// Returns the latest occurrence after the job was checked and no later
+// than now. Windows ending at now are doubled from a minute until one
+// holds an occurrence, so catching up after a long outage only steps
+// through the occurrences near now rather than every one missed.
+func (j *job) due(now time.Time) (time.Time, bool) {
+	span := now.Sub(j.Checked)
+	for w := time.Minute; ; {
+		start := j.Checked
+		if w < span {
+			start = now.Add(-w)
+		}
+		if next, ok := j.trigger.Next(start); ok && !next.After(now) {
+			return j.latest(next, now), true
+		}
+		if w >= span {
+			return time.Time{}, false
+		}
+		if w > span/2 {
+			w = span
+		} else {
+			w *= 2
+		}
+	}
+}
+
+// Returns the last occurrence from t up to now
+func (j *job) latest(t, now time.Time) time.Time {
+	for {
+		next, ok := j.trigger.Next(t)
+		if !ok || next.After(now) {
+			return t
+		}
+		t = next
+	}
+}
+
+// Runs jobs on their schedule, writing through Tracker so scene recalls
+// start from the state left by earlier commands
+type Scheduler struct {
+	// Exported Fields
+	Tracker   *lightswarm.Tracker
+	Scenes    map[string]lightswarm.Scene // Scenes available to actions
+	Latitude  float64                     // Degrees north
+	Longitude float64                     // Degrees east
+	Location  *time.Location              // Location cron expressions are evaluated in, defaults to time.Local
+	Path      string                      // File the job list is saved to, empty to not save
+	ErrorLog  *log.Logger                 // Logs failed jobs, defaults to the log package
+
+	mu      sync.Mutex
+	jobs    []*job
+	changed chan struct{}
+
+	// Overridden in tests
+	now   func() time.Time
+	after func(d time.Duration) <-chan time.Time
+}
+
+// Returns the location cron expressions are evaluated in
+func (s *Scheduler) location() *time.Location {
+	if s.Location == nil {
+		return time.Local
+	}
+	return s.Location
+}
+
+// Logs to ErrorLog or the standard logger
+func (s *Scheduler) logf(format string, args ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// Parses a cron expression or sun event, evaluated in the scheduler
+// location
+func (s *Scheduler) trigger(when string) (Trigger, error) {
+	name := strings.ToLower(strings.TrimSpace(when))
+	for event := range sunEventNames {
+		if strings.HasPrefix(name, event) {
+			t, err := ParseSunTrigger(when, s.Latitude, s.Longitude)
+			if err != nil {
+				return nil, err
+			}
+			return inLocation{t, s.location()}, nil
+		}
+	}
+	c, err := ParseCron(when)
+	if err != nil {
+		return nil, err
+	}
+	return inLocation{c, s.location()}, nil
+}
+
+// A trigger evaluated in a location, so days start at local midnight
+type inLocation struct {
+	trigger  Trigger
+	location *time.Location
+}
+
+func (t inLocation) Next(after time.Time) (time.Time, bool) {
+	return t.trigger.Next(after.In(t.location))
+}
+
+// Checks the job can be scheduled and run
+func (s *Scheduler) validate(j Job) (Trigger, error) {
+	if j.Name == "" {
+		return nil, ErrNoJobName
+	}
+	trigger, err := s.trigger(j.When)
+	if err != nil {
+		return nil, err
+	}
+	a := j.Action
+	switch {
+	case a.Scene != "" && a.Command != "":
+		return nil, ErrInvalidAction
+	case a.Scene != "":
+		if _, ok := s.Scenes[a.Scene]; !ok {
+			return nil, ErrUnknownScene
+		}
+	default:
+		if _, err := a.command(); err != nil {
+			return nil, err
+		}
+	}
+	return trigger, nil
+}
+
+// Signals the run loop that the jobs have changed
+func (s *Scheduler) notify() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Adds a job, replacing any job with the same name, and saves the job
+// list. New jobs are checked from now so past occurrences are not run.
+func (s *Scheduler) Add(j Job) error {
+	trigger, err := s.validate(j)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if j.Checked.IsZero() {
+		j.Checked = s.now()
+	}
+	added := &job{j, trigger}
+	replaced := false
+	for i, existing := range s.jobs {
+		if existing.Name == j.Name {
+			s.jobs[i], replaced = added, true
+		}
+	}
+	if !replaced {
+		s.jobs = append(s.jobs, added)
+	}
+	err = s.save()
+	s.mu.Unlock()
+	s.notify()
+	return err
+}
+
+// Removes the named job and saves the job list
+func (s *Scheduler) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, j := range s.jobs {
+		if j.Name == name {
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			return s.save()
+		}
+	}
+	return ErrUnknownJob
+}
+
+// Returns every job in the order they were added
+func (s *Scheduler) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]Job, len(s.jobs))
+	for i, j := range s.jobs {
+		jobs[i] = j.Job
+	}
+	return jobs
+}
+
+// Returns the next time the named job will run
+func (s *Scheduler) Next(name string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.Name == name {
+			return j.trigger.Next(j.Checked)
+		}
+	}
+	return time.Time{}, false
+}
+
+// Replaces the jobs with those saved at Path, a missing file is an empty
+// job list
+func (s *Scheduler) Load() error {
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved []Job
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	jobs := []*job{}
+	for _, j := range saved {
+		trigger, err := s.validate(j)
+		if err != nil {
+			return fmt.Errorf("schedule: job %q: %s", j.Name, err)
+		}
+		if j.Checked.IsZero() {
+			j.Checked = s.now()
+		}
+		jobs = append(jobs, &job{j, trigger})
+	}
+	s.mu.Lock()
+	s.jobs = jobs
+	s.mu.Unlock()
+	s.notify()
+	return nil
+}
+
+// Writes the job list to Path, replacing the file atomically
+func (s *Scheduler) save() error {
+	if s.Path == "" {
+		return nil
+	}
+	jobs := make([]Job, len(s.jobs))
+	for i, j := range s.jobs {
+		jobs[i] = j.Job
+	}
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.Path)
+}
+
+// Runs the action of a job
+func (s *Scheduler) run(ctx context.Context, a Action) error {
+	if a.Scene != "" {
+		_, _, err := s.Tracker.RecallContext(ctx, s.Scenes[a.Scene], time.Duration(a.Transition))
+		return err
+	}
+	cmd, err := a.command()
+	if err != nil {
+		return err
+	}
+	var l lightswarm.ContextLight = lightswarm.New(a.Addr, s.Tracker)
+	if a.Group {
+		l = lightswarm.NewGroup(a.Addr, s.Tracker)
+	}
+	_, _, err = l.SendContext(ctx, cmd)
+	return err
+}
+
+// Runs every job with an occurrence due by now, in the order they were
+// due, and marks every job checked up to now. When catching up jobs
+// without a catch up policy are skipped.
+func (s *Scheduler) runDue(ctx context.Context, now time.Time, catchUp bool) {
+	type dueJob struct {
+		at     time.Time
+		name   string
+		action Action
+	}
+	s.mu.Lock()
+	due := []dueJob{}
+	for _, j := range s.jobs {
+		if at, ok := j.due(now); ok && (!catchUp || j.CatchUp == CatchUpLast) {
+			due = append(due, dueJob{at, j.Name, j.Action})
+		}
+		j.Checked = now
+	}
+	s.mu.Unlock()
+	sort.SliceStable(due, func(i, k int) bool { return due[i].at.Before(due[k].at) })
+	for _, d := range due {
+		if err := s.run(ctx, d.action); err != nil {
+			s.logf("schedule: %s: %s", d.name, err)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.save(); err != nil {
+		s.logf("schedule: save: %s", err)
+	}
+}
+
+// Returns the earliest time any job is next due
+func (s *Scheduler) next() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var earliest time.Time
+	for _, j := range s.jobs {
+		if t, ok := j.trigger.Next(j.Checked); ok && (earliest.IsZero() || t.Before(earliest)) {
+			earliest = t
+		}
+	}
+	return earliest, !earliest.IsZero()
+}
+
+// Catches up missed jobs then runs jobs as they become due until the
+// context is done
+func (s *Scheduler) Run(ctx context.Context) error {
+	// changes made before running are picked up below
+	select {
+	case <-s.changed:
+	default:
+	}
+	s.runDue(ctx, s.now(), true)
+	for {
+		var timer <-chan time.Time
+		if next, ok := s.next(); ok {
+			timer = s.after(next.Sub(s.now()))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.changed:
+			continue
+		case <-timer:
+		}
+		s.runDue(ctx, s.now(), false)
+	}
+}
+
+// Constructs a new Scheduler writing through the tracker at the given
+// latitude and longitude
+func NewScheduler(tracker *lightswarm.Tracker, lat, lon float64) *Scheduler {
+	return &Scheduler{
+		Tracker:   tracker,
+		Latitude:  lat,
+		Longitude: lon,
+		changed:   make(chan struct{}, 1),
+		now:       time.Now,
+		after:     time.After,
+	}
+}
@@ -0,0 +1,245 @@
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thisissoon/lightswarm"
+)
+
+// A controllable clock
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = t
+}
+
+// Constructs a Scheduler in UTC using a fake clock
+func fakeScheduler(now time.Time) (*Scheduler, *fakeClock, *bytes.Buffer) {
+	buff := bytes.NewBuffer(nil)
+	s := NewScheduler(lightswarm.NewTracker(buff), london[0], london[1])
+	s.Location = time.UTC
+	s.ErrorLog = log.New(ioutil.Discard, "", 0)
+	clock := &fakeClock{t: now}
+	s.now = clock.now
+	return s, clock, buff
+}
+
+// Returns the encoded frame
+func frame(addr uint16, cmd byte, args ...byte) []byte {
+	return lightswarm.Frame{Addr: addr, Cmd: cmd, CmdArgs: args}.Bytes()
+}
+
+func TestDurationText(t *testing.T) {
+	var a Action
+	assert.Nil(t, json.Unmarshal([]byte(`{"transition": "1m30s"}`), &a))
+	assert.Equal(t, Duration(time.Second*90), a.Transition)
+	b, err := json.Marshal(a)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"transition":"1m30s"}`, string(b))
+	assert.NotNil(t, json.Unmarshal([]byte(`{"transition": "soon"}`), &a))
+}
+
+func TestSchedulerAddInvalid(t *testing.T) {
+	s, _, _ := fakeScheduler(time.Unix(0, 0))
+	s.Scenes = map[string]lightswarm.Scene{"opening": {}}
+	tt := []struct {
+		name string
+		job  Job
+		err  error
+	}{
+		{"no name", Job{When: "@daily", Action: Action{Command: "on"}}, ErrNoJobName},
+		{"bad cron", Job{Name: "a", When: "* *", Action: Action{Command: "on"}}, ErrInvalidCron},
+		{"bad sun event", Job{Name: "a", When: "sunset+later", Action: Action{Command: "on"}}, ErrInvalidSunEvent},
+		{"unknown scene", Job{Name: "a", When: "@daily", Action: Action{Scene: "closing"}}, ErrUnknownScene},
+		{"unknown command", Job{Name: "a", When: "@daily", Action: Action{Command: "explode"}}, ErrInvalidAction},
+		{"wrong arguments", Job{Name: "a", When: "@daily", Action: Action{Command: "level"}}, ErrInvalidAction},
+		{"argument out of range", Job{Name: "a", When: "@daily", Action: Action{Command: "level", Args: []int{256}}}, ErrInvalidAction},
+		{"scene and command", Job{Name: "a", When: "@daily", Action: Action{Scene: "opening", Command: "on"}}, ErrInvalidAction},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.err, s.Add(tc.job))
+		})
+	}
+	assert.Empty(t, s.Jobs())
+}
+
+func TestSchedulerJobs(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	s, _, _ := fakeScheduler(now)
+	assert.Nil(t, s.Add(Job{Name: "on", When: "0 18 * * *", Action: Action{Addr: 690, Command: "on"}}))
+	assert.Nil(t, s.Add(Job{Name: "off", When: "0 23 * * *", Action: Action{Addr: 690, Command: "off"}}))
+	assert.Nil(t, s.Add(Job{Name: "on", When: "0 19 * * *", Action: Action{Addr: 690, Command: "on"}}))
+	jobs := s.Jobs()
+	assert.Len(t, jobs, 2)
+	assert.Equal(t, "0 19 * * *", jobs[0].When)
+	assert.Equal(t, now, jobs[0].Checked)
+	next, ok := s.Next("on")
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 1, 10, 19, 0, 0, 0, time.UTC), next)
+	_, ok = s.Next("missing")
+	assert.False(t, ok)
+	assert.Nil(t, s.Remove("on"))
+	assert.Equal(t, ErrUnknownJob, s.Remove("on"))
+	assert.Len(t, s.Jobs(), 1)
+}
+
+func TestSchedulerPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schedule")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	s, _, _ := fakeScheduler(now)
+	s.Path = filepath.Join(dir, "jobs.json")
+	// missing file is no jobs
+	assert.Nil(t, s.Load())
+	job := Job{Name: "dusk", When: "dusk-15m", CatchUp: CatchUpLast, Action: Action{Addr: 4096, Group: true, Command: "fade", Args: []int{255, 1, 5}}}
+	assert.Nil(t, s.Add(job))
+	loaded, _, _ := fakeScheduler(now)
+	loaded.Path = s.Path
+	assert.Nil(t, loaded.Load())
+	job.Checked = now
+	assert.Equal(t, []Job{job}, loaded.Jobs())
+	// invalid saved jobs are reported
+	ioutil.WriteFile(s.Path, []byte(`[{"name": "bad", "when": "never"}]`), 0644)
+	assert.EqualError(t, loaded.Load(), `schedule: job "bad": schedule: invalid cron expression`)
+}
+
+func TestSchedulerRunDue(t *testing.T) {
+	now := time.Date(2024, 1, 10, 6, 0, 0, 0, time.UTC)
+	s, _, buff := fakeScheduler(now)
+	s.Add(Job{Name: "morning", When: "0 7 * * *", Action: Action{Addr: 690, Command: "level", Args: []int{128}}})
+	s.Add(Job{Name: "evening", When: "0 18 * * *", Action: Action{Addr: 690, Command: "off"}})
+	s.runDue(context.Background(), now.Add(time.Minute*90), false)
+	assert.Equal(t, frame(690, lightswarm.SET_LEVEL, 128), buff.Bytes())
+	for _, j := range s.Jobs() {
+		assert.Equal(t, now.Add(time.Minute*90), j.Checked)
+	}
+	// nothing more due
+	buff.Reset()
+	s.runDue(context.Background(), now.Add(time.Hour*2), false)
+	assert.Empty(t, buff.Bytes())
+}
+
+// A trigger at fixed times, counting calls to Next
+type listTrigger struct {
+	times []time.Time
+	calls int
+}
+
+func (l *listTrigger) Next(after time.Time) (time.Time, bool) {
+	l.calls++
+	for _, t := range l.times {
+		if t.After(after) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Counts calls to Next of the wrapped trigger
+type countingTrigger struct {
+	Trigger
+	calls int
+}
+
+func (c *countingTrigger) Next(after time.Time) (time.Time, bool) {
+	c.calls++
+	return c.Trigger.Next(after)
+}
+
+func TestJobDue(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 30, 30, 0, time.UTC)
+	cron, err := ParseCron("* * * * *")
+	assert.Nil(t, err)
+	every := &countingTrigger{Trigger: inLocation{cron, time.UTC}}
+	// a year of missed minutes only steps through those near now
+	at, ok := (&job{Job: Job{Checked: now.AddDate(-1, 0, 0)}, trigger: every}).due(now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 1, 10, 12, 30, 0, 0, time.UTC), at)
+	assert.True(t, every.calls < 10, "%d calls", every.calls)
+	list := &listTrigger{times: []time.Time{now.Add(-time.Hour * 50), now.Add(-time.Hour * 49), now.Add(time.Hour)}}
+	tt := []struct {
+		name     string
+		checked  time.Time
+		expected time.Time
+	}{
+		{"latest of several", now.Add(-time.Hour * 100), now.Add(-time.Hour * 49)},
+		{"none since checked", now.Add(-time.Hour * 49), time.Time{}},
+		{"checked in the future", now.Add(time.Hour * 2), time.Time{}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			at, ok := (&job{Job: Job{Checked: tc.checked}, trigger: list}).due(now)
+			assert.Equal(t, !tc.expected.IsZero(), ok)
+			assert.Equal(t, tc.expected, at)
+		})
+	}
+}
+
+func TestSchedulerCatchUp(t *testing.T) {
+	checked := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	s, _, buff := fakeScheduler(checked)
+	s.Scenes = map[string]lightswarm.Scene{
+		"evening": {Targets: map[uint16]lightswarm.Target{690: {RGB: &lightswarm.Color{R: 255}}}},
+	}
+	// added in reverse so catch up must sort by time
+	s.Add(Job{Name: "off", When: "0 23 * * *", CatchUp: CatchUpLast, Action: Action{Addr: 690, Command: "off"}})
+	s.Add(Job{Name: "on", When: "0 18 * * *", CatchUp: CatchUpLast, Action: Action{Addr: 690, Command: "on"}})
+	s.Add(Job{Name: "scene", When: "0 19 * * *", CatchUp: CatchUpLast, Action: Action{Scene: "evening"}})
+	s.Add(Job{Name: "skipped", When: "0 20 * * *", Action: Action{Addr: 227, Command: "on"}})
+	// restarted two days later
+	s.runDue(context.Background(), checked.Add(time.Hour*37), true)
+	expected := frame(690, lightswarm.ON)
+	expected = append(expected, frame(690, lightswarm.SET_RGB_LEVELS, 255, 0, 0)...)
+	expected = append(expected, frame(690, lightswarm.OFF)...)
+	assert.Equal(t, expected, buff.Bytes())
+}
+
+func TestSchedulerRun(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	s, clock, buff := fakeScheduler(now)
+	waits := make(chan time.Duration)
+	fire := make(chan time.Time)
+	s.after = func(d time.Duration) <-chan time.Time {
+		waits <- d
+		return fire
+	}
+	s.Add(Job{Name: "tick", When: "* * * * *", Action: Action{Addr: 690, Command: "toggle"}})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	assert.Equal(t, time.Minute, <-waits)
+	clock.set(now.Add(time.Minute))
+	fire <- clock.now()
+	assert.Equal(t, time.Minute, <-waits)
+	// adding a job wakes the scheduler
+	s.Add(Job{Name: "soon", When: "* * * * *", Action: Action{Addr: 227, Command: "on"}})
+	assert.Equal(t, time.Minute, <-waits)
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+	assert.Equal(t, frame(690, lightswarm.TOGGLE), buff.Bytes())
+}
@@ -0,0 +1,144 @@
+package schedule
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+// Returned when parsing an invalid sun event
+var ErrInvalidSunEvent = errors.New("schedule: invalid sun event")
+
+// A daily solar event
+type SunEvent int
+
+// Solar events, dawn and dusk are civil twilight when the sun is 6° below
+// the horizon
+const (
+	Sunrise SunEvent = iota
+	Sunset
+	Dawn
+	Dusk
+)
+
+// Event names as used in trigger expressions
+var sunEventNames = map[string]SunEvent{
+	"sunrise": Sunrise,
+	"sunset":  Sunset,
+	"dawn":    Dawn,
+	"dusk":    Dusk,
+}
+
+// Returns the name of the event
+func (e SunEvent) String() string {
+	for name, event := range sunEventNames {
+		if event == e {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// Returns the altitude of the sun centre in degrees at the event, sunrise
+// and sunset allow for refraction and the solar disc
+func (e SunEvent) altitude() float64 {
+	if e == Dawn || e == Dusk {
+		return -6
+	}
+	return -0.833
+}
+
+// Reports whether the event is in the morning
+func (e SunEvent) rising() bool {
+	return e == Sunrise || e == Dawn
+}
+
+// Julian dates
+const (
+	unixEpochJD = 2440587.5
+	j2000       = 2451545.0
+)
+
+// Degree trigonometry
+func sin(deg float64) float64 { return math.Sin(deg * math.Pi / 180) }
+func cos(deg float64) float64 { return math.Cos(deg * math.Pi / 180) }
+
+// Returns the time of the event on the date at the latitude and longitude
+// in degrees, north and east positive, using the sunrise equation. There is
+// no event during polar day or night. Times are accurate to around a
+// minute.
+func SunTime(event SunEvent, date time.Time, lat, lon float64) (time.Time, bool) {
+	noon := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, time.UTC)
+	n := float64(noon.Unix())/86400 + unixEpochJD - j2000 + 0.0008
+	// mean solar time
+	j := n - lon/360
+	// solar mean anomaly
+	m := math.Mod(357.5291+0.98560028*j, 360)
+	// equation of the centre
+	c := 1.9148*sin(m) + 0.0200*sin(2*m) + 0.0003*sin(3*m)
+	// ecliptic longitude
+	l := math.Mod(m+c+180+102.9372, 360)
+	transit := j2000 + j + 0.0053*sin(m) - 0.0069*sin(2*l)
+	// declination of the sun
+	sinDecl := sin(l) * sin(23.4397)
+	cosDecl := math.Cos(math.Asin(sinDecl))
+	cosHour := (sin(event.altitude()) - sin(lat)*sinDecl) / (cos(lat) * cosDecl)
+	if cosHour < -1 || cosHour > 1 {
+		return time.Time{}, false
+	}
+	hour := math.Acos(cosHour) * 180 / math.Pi
+	jd := transit + hour/360
+	if event.rising() {
+		jd = transit - hour/360
+	}
+	secs := (jd - unixEpochJD) * 86400
+	return time.Unix(0, int64(secs*float64(time.Second))).In(date.Location()), true
+}
+
+// Fires at a solar event offset by a duration, for example 30 minutes
+// before dusk
+type SunTrigger struct {
+	Event     SunEvent
+	Offset    time.Duration
+	Latitude  float64
+	Longitude float64
+}
+
+// Returns the first event after the given time, dates are taken in the
+// location of the given time
+func (s SunTrigger) Next(after time.Time) (time.Time, bool) {
+	date := after.AddDate(0, 0, -1)
+	// polar day or night can last around six months
+	for i := 0; i < 367; i++ {
+		if t, ok := SunTime(s.Event, date, s.Latitude, s.Longitude); ok {
+			if t = t.Add(s.Offset); t.After(after) {
+				return t, true
+			}
+		}
+		date = date.AddDate(0, 0, 1)
+	}
+	return time.Time{}, false
+}
+
+// Parses a sun event with an optional offset such as "sunset", "dusk-30m"
+// or "sunrise+1h"
+func ParseSunTrigger(s string, lat, lon float64) (SunTrigger, error) {
+	name, offset := strings.ToLower(strings.TrimSpace(s)), ""
+	if i := strings.IndexAny(name, "+-"); i >= 0 {
+		name, offset = name[:i], name[i:]
+	}
+	event, ok := sunEventNames[name]
+	if !ok {
+		return SunTrigger{}, ErrInvalidSunEvent
+	}
+	t := SunTrigger{Event: event, Latitude: lat, Longitude: lon}
+	if offset != "" {
+		d, err := time.ParseDuration(offset)
+		if err != nil {
+			return SunTrigger{}, ErrInvalidSunEvent
+		}
+		t.Offset = d
+	}
+	return t, nil
+}
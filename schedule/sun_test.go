@@ -0,0 +1,100 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Venue locations
+var (
+	london = [2]float64{51.5074, -0.1278}
+	sydney = [2]float64{-33.8688, 151.2093}
+	tromso = [2]float64{69.6492, 18.9553}
+	bst    = time.FixedZone("BST", 3600)
+	aedt   = time.FixedZone("AEDT", 11*3600)
+)
+
+// Asserts the times are within three minutes of each other
+func assertNear(t *testing.T, expected, actual time.Time) {
+	d := actual.Sub(expected)
+	assert.True(t, d > -time.Minute*3 && d < time.Minute*3, "expected %s, got %s", expected, actual)
+}
+
+func TestSunTime(t *testing.T) {
+	midsummer := time.Date(2024, 6, 21, 0, 0, 0, 0, bst)
+	tt := []struct {
+		name     string
+		event    SunEvent
+		date     time.Time
+		place    [2]float64
+		expected time.Time
+	}{
+		{"london sunrise", Sunrise, midsummer, london, time.Date(2024, 6, 21, 4, 43, 0, 0, bst)},
+		{"london sunset", Sunset, midsummer, london, time.Date(2024, 6, 21, 21, 21, 0, 0, bst)},
+		{"london dawn", Dawn, midsummer, london, time.Date(2024, 6, 21, 3, 56, 0, 0, bst)},
+		{"london dusk", Dusk, midsummer, london, time.Date(2024, 6, 21, 22, 10, 0, 0, bst)},
+		{"sydney sunrise", Sunrise, time.Date(2024, 12, 21, 0, 0, 0, 0, aedt), sydney, time.Date(2024, 12, 21, 5, 41, 0, 0, aedt)},
+		{"sydney sunset", Sunset, time.Date(2024, 12, 21, 0, 0, 0, 0, aedt), sydney, time.Date(2024, 12, 21, 20, 5, 0, 0, aedt)},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, ok := SunTime(tc.event, tc.date, tc.place[0], tc.place[1])
+			assert.True(t, ok)
+			assertNear(t, tc.expected, actual)
+			assert.Equal(t, tc.date.Location(), actual.Location())
+		})
+	}
+}
+
+func TestSunTimePolar(t *testing.T) {
+	_, ok := SunTime(Sunset, time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC), tromso[0], tromso[1])
+	assert.False(t, ok)
+	_, ok = SunTime(Sunrise, time.Date(2024, 12, 21, 0, 0, 0, 0, time.UTC), tromso[0], tromso[1])
+	assert.False(t, ok)
+}
+
+func TestSunTriggerNext(t *testing.T) {
+	trigger := SunTrigger{Event: Sunset, Offset: -time.Minute * 30, Latitude: london[0], Longitude: london[1]}
+	// before today's event
+	next, ok := trigger.Next(time.Date(2024, 6, 21, 12, 0, 0, 0, bst))
+	assert.True(t, ok)
+	assertNear(t, time.Date(2024, 6, 21, 20, 51, 0, 0, bst), next)
+	// after today's event
+	next, ok = trigger.Next(time.Date(2024, 6, 21, 21, 0, 0, 0, bst))
+	assert.True(t, ok)
+	assertNear(t, time.Date(2024, 6, 22, 20, 51, 0, 0, bst), next)
+	// the end of polar day
+	polar := SunTrigger{Event: Sunset, Latitude: tromso[0], Longitude: tromso[1]}
+	next, ok = polar.Next(time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, time.July, next.Month())
+}
+
+func TestParseSunTrigger(t *testing.T) {
+	tt := []struct {
+		s        string
+		expected SunTrigger
+		err      error
+	}{
+		{"sunset", SunTrigger{Event: Sunset, Latitude: 1, Longitude: 2}, nil},
+		{"Dusk-30m", SunTrigger{Event: Dusk, Offset: -time.Minute * 30, Latitude: 1, Longitude: 2}, nil},
+		{" sunrise+1h15m ", SunTrigger{Event: Sunrise, Offset: time.Minute * 75, Latitude: 1, Longitude: 2}, nil},
+		{"dawn", SunTrigger{Event: Dawn, Latitude: 1, Longitude: 2}, nil},
+		{"noon", SunTrigger{}, ErrInvalidSunEvent},
+		{"sunset+soon", SunTrigger{}, ErrInvalidSunEvent},
+	}
+	for _, tc := range tt {
+		t.Run(tc.s, func(t *testing.T) {
+			trigger, err := ParseSunTrigger(tc.s, 1, 2)
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.expected, trigger)
+		})
+	}
+}
+
+func TestSunEventString(t *testing.T) {
+	assert.Equal(t, "dusk", Dusk.String())
+	assert.Equal(t, "unknown", SunEvent(10).String())
+}
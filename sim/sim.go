@@ -0,0 +1,289 @@
+/*
+A software simulation of a network of LightSwarm nodes.
+
+A Network implements io.Writer so it can be handed to lightswarm.New in
+place of a serial port. Frames written to it are decoded and applied to
+the state of every node they address, fades progress in simulated time
+as the network is advanced.
+
+	net := sim.New(690)
+	led := lightswarm.New(690, net)
+	led.Fade(lightswarm.Fade{Level: 255, Interval: 1, Step: 5})
+	net.Advance(time.Second)
+	state, _ := net.State(690)
+*/
+package sim
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/thisissoon/lightswarm"
+)
+
+// Snapshot of a simulated node's state
+type State struct {
+	On     bool
+	Level  byte
+	RGB    [3]byte
+	Pseudo []uint16 // Psuedo address table, sorted
+	Fading bool     // True while any fade is in progress
+}
+
+// A fade in progress on a single channel
+type fade struct {
+	target   byte
+	step     int
+	interval time.Duration
+	next     time.Duration // simulated time of the next step
+}
+
+// A single level channel on a node
+type channel struct {
+	level byte
+	fade  *fade
+}
+
+// Sets the channel level immediately, cancelling any fade
+func (c *channel) set(level byte) {
+	c.level = level
+	c.fade = nil
+}
+
+// Starts a fade from the current level, the first step is applied after
+// one interval
+func (c *channel) start(f lightswarm.Fade, now time.Duration) {
+	fd := &fade{
+		target:   byte(f.Level),
+		step:     f.Step,
+		interval: time.Duration(f.Interval) * lightswarm.FadeInterval,
+	}
+	if fd.step < 1 {
+		fd.step = 1
+	}
+	if fd.interval < lightswarm.FadeInterval {
+		fd.interval = lightswarm.FadeInterval
+	}
+	fd.next = now + fd.interval
+	c.fade = fd
+	if c.level == fd.target {
+		c.fade = nil
+	}
+}
+
+// Applies any fade steps due up to the given time
+func (c *channel) advance(now time.Duration) {
+	for c.fade != nil && c.fade.next <= now {
+		level, target := int(c.level), int(c.fade.target)
+		if level < target {
+			level += c.fade.step
+			if level > target {
+				level = target
+			}
+		} else {
+			level -= c.fade.step
+			if level < target {
+				level = target
+			}
+		}
+		c.level = byte(level)
+		c.fade.next += c.fade.interval
+		if c.level == c.fade.target {
+			c.fade = nil
+		}
+	}
+}
+
+// A single simulated node
+type node struct {
+	addr   uint16
+	on     bool
+	level  channel
+	rgb    [3]channel
+	pseudo map[uint16]bool
+}
+
+// Reports whether the node responds to frames sent to the given address
+func (n *node) responds(addr uint16) bool {
+	return addr == n.addr || n.pseudo[addr]
+}
+
+// Applies any fade steps due up to the given time
+func (n *node) advance(now time.Duration) {
+	n.level.advance(now)
+	for i := range n.rgb {
+		n.rgb[i].advance(now)
+	}
+}
+
+// Returns a snapshot of the node's state
+func (n *node) state() State {
+	s := State{
+		On:     n.on,
+		Level:  n.level.level,
+		Fading: n.level.fade != nil,
+		Pseudo: []uint16{},
+	}
+	for i, c := range n.rgb {
+		s.RGB[i] = c.level
+		s.Fading = s.Fading || c.fade != nil
+	}
+	for addr := range n.pseudo {
+		s.Pseudo = append(s.Pseudo, addr)
+	}
+	sort.Slice(s.Pseudo, func(i, j int) bool { return s.Pseudo[i] < s.Pseudo[j] })
+	return s
+}
+
+// Applies the command to the node
+func (n *node) apply(cmd lightswarm.Command, now time.Duration) {
+	switch c := cmd.(type) {
+	case *lightswarm.On:
+		n.on = true
+	case *lightswarm.Off:
+		n.on = false
+	case *lightswarm.Toggle:
+		n.on = !n.on
+	case *lightswarm.SetLevel:
+		n.level.set(c.Level)
+	case *lightswarm.FadeToLevel:
+		n.level.start(c.Fade, now)
+	case *lightswarm.FadeDown:
+		if c.Fade.Level < int(n.level.level) {
+			n.level.start(c.Fade, now)
+		}
+	case *lightswarm.SetPseudoAddress:
+		n.pseudo[c.Addr] = true
+	case *lightswarm.ErasePseudoAddressTable:
+		n.pseudo = map[uint16]bool{}
+	case *lightswarm.SetRGBLevels:
+		n.rgb[0].set(c.R)
+		n.rgb[1].set(c.G)
+		n.rgb[2].set(c.B)
+	case *lightswarm.FadeRGBToLevel:
+		n.rgb[0].start(c.R, now)
+		n.rgb[1].start(c.G, now)
+		n.rgb[2].start(c.B, now)
+	case *lightswarm.FadeMultipleToLevel:
+		for _, af := range c.Fades {
+			if n.responds(af.Addr) {
+				n.level.start(af.Fade, now)
+			}
+		}
+	}
+}
+
+// A simulated network of LightSwarm nodes, the zero value is an empty
+// network
+type Network struct {
+	mu     sync.Mutex
+	now    time.Duration
+	nodes  map[uint16]*node
+	buf    []byte
+	errors []error
+}
+
+// Adds nodes with the given physical addresses to the network
+func (net *Network) Add(addrs ...uint16) {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	if net.nodes == nil {
+		net.nodes = map[uint16]*node{}
+	}
+	for _, addr := range addrs {
+		if _, ok := net.nodes[addr]; ok {
+			continue
+		}
+		net.nodes[addr] = &node{
+			addr:   addr,
+			pseudo: map[uint16]bool{},
+		}
+	}
+}
+
+// Decodes the frames in the written bytes and applies them to the nodes
+// they address. Partial frames are buffered until the rest is written.
+// As with real hardware bad frames never cause the write to fail, they
+// are recorded and can be inspected with Errors.
+func (net *Network) Write(p []byte) (int, error) {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	net.buf = append(net.buf, p...)
+	for {
+		advance, token, _ := lightswarm.ScanFrames(net.buf, false)
+		if advance == 0 {
+			break
+		}
+		if token != nil {
+			net.receive(token)
+		}
+		net.buf = net.buf[advance:]
+	}
+	return len(p), nil
+}
+
+// Decodes a single frame and applies it to every node it addresses
+func (net *Network) receive(raw []byte) {
+	frame, err := lightswarm.ParseFrame(raw)
+	var cmd lightswarm.Command
+	if err == nil {
+		cmd, err = frame.Command()
+	}
+	if err != nil {
+		net.errors = append(net.errors, &lightswarm.FrameError{
+			Raw: append([]byte{}, raw...),
+			Err: err,
+		})
+		return
+	}
+	// FADE_MULTIPLE_TO_LEVEL carries its own addresses
+	_, multiple := cmd.(*lightswarm.FadeMultipleToLevel)
+	for _, n := range net.nodes {
+		if multiple || n.responds(frame.Addr) {
+			n.apply(cmd, net.now)
+		}
+	}
+}
+
+// Advances simulated time, progressing any fades in progress
+func (net *Network) Advance(d time.Duration) {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	net.now += d
+	for _, n := range net.nodes {
+		n.advance(net.now)
+	}
+}
+
+// Returns the current simulated time since the network was created
+func (net *Network) Now() time.Duration {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	return net.now
+}
+
+// Returns the state of the node with the given physical address
+func (net *Network) State(addr uint16) (State, bool) {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	n, ok := net.nodes[addr]
+	if !ok {
+		return State{}, false
+	}
+	return n.state(), true
+}
+
+// Returns the errors for any frames that could not be applied
+func (net *Network) Errors() []error {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	return append([]error{}, net.errors...)
+}
+
+// Constructs a new Network with nodes at the given physical addresses
+func New(addrs ...uint16) *Network {
+	net := &Network{}
+	net.Add(addrs...)
+	return net
+}
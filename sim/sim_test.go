@@ -0,0 +1,304 @@
+package sim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thisissoon/lightswarm"
+)
+
+func TestChannelAdvance(t *testing.T) {
+	tt := []struct {
+		name     string
+		level    byte
+		fade     lightswarm.Fade
+		elapsed  time.Duration
+		expected byte
+		fading   bool
+	}{
+		{
+			"no time elapsed",
+			0,
+			lightswarm.Fade{Level: 255, Interval: 1, Step: 1},
+			0,
+			0,
+			true,
+		},
+		{
+			"one interval elapsed",
+			0,
+			lightswarm.Fade{Level: 255, Interval: 1, Step: 10},
+			lightswarm.FadeInterval,
+			10,
+			true,
+		},
+		{
+			"part way through interval",
+			0,
+			lightswarm.Fade{Level: 255, Interval: 2, Step: 10},
+			lightswarm.FadeInterval * 3,
+			10,
+			true,
+		},
+		{
+			"fade up complete",
+			0,
+			lightswarm.Fade{Level: 255, Interval: 1, Step: 10},
+			lightswarm.FadeInterval * 26,
+			255,
+			false,
+		},
+		{
+			"fade down",
+			200,
+			lightswarm.Fade{Level: 100, Interval: 1, Step: 30},
+			lightswarm.FadeInterval * 2,
+			140,
+			true,
+		},
+		{
+			"fade down does not overshoot",
+			200,
+			lightswarm.Fade{Level: 100, Interval: 1, Step: 30},
+			lightswarm.FadeInterval * 4,
+			100,
+			false,
+		},
+		{
+			"zero step and interval are treated as 1",
+			0,
+			lightswarm.Fade{Level: 255, Interval: 0, Step: 0},
+			lightswarm.FadeInterval * 5,
+			5,
+			true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &channel{level: tc.level}
+			c.start(tc.fade, 0)
+			c.advance(tc.elapsed)
+			assert.Equal(t, tc.expected, c.level)
+			assert.Equal(t, tc.fading, c.fade != nil)
+		})
+	}
+}
+
+func TestNetworkCommands(t *testing.T) {
+	tt := []struct {
+		name     string
+		cmds     func(led *lightswarm.LED)
+		elapsed  time.Duration
+		expected State
+	}{
+		{
+			"on",
+			func(led *lightswarm.LED) { led.On() },
+			0,
+			State{On: true, Pseudo: []uint16{}},
+		},
+		{
+			"on then off",
+			func(led *lightswarm.LED) { led.On(); led.Off() },
+			0,
+			State{On: false, Pseudo: []uint16{}},
+		},
+		{
+			"toggle",
+			func(led *lightswarm.LED) { led.Toggle() },
+			0,
+			State{On: true, Pseudo: []uint16{}},
+		},
+		{
+			"set level",
+			func(led *lightswarm.LED) { led.SetLevel(128) },
+			0,
+			State{Level: 128, Pseudo: []uint16{}},
+		},
+		{
+			"set level cancels fade",
+			func(led *lightswarm.LED) {
+				led.Fade(lightswarm.Fade{Level: 255, Interval: 1, Step: 1})
+				led.SetLevel(10)
+			},
+			time.Second,
+			State{Level: 10, Pseudo: []uint16{}},
+		},
+		{
+			"fade in progress",
+			func(led *lightswarm.LED) { led.Fade(lightswarm.Fade{Level: 255, Interval: 1, Step: 5}) },
+			lightswarm.FadeInterval * 10,
+			State{Level: 50, Fading: true, Pseudo: []uint16{}},
+		},
+		{
+			"fade complete",
+			func(led *lightswarm.LED) { led.Fade(lightswarm.Fade{Level: 255, Interval: 1, Step: 5}) },
+			time.Second,
+			State{Level: 255, Pseudo: []uint16{}},
+		},
+		{
+			"legacy fade down ignores higher level",
+			func(led *lightswarm.LED) { led.FadeDown(lightswarm.Fade{Level: 255, Interval: 1, Step: 5}) },
+			time.Second,
+			State{Level: 0, Pseudo: []uint16{}},
+		},
+		{
+			"legacy fade down",
+			func(led *lightswarm.LED) {
+				led.SetLevel(100)
+				led.FadeDown(lightswarm.Fade{Level: 50, Interval: 1, Step: 5})
+			},
+			time.Second,
+			State{Level: 50, Pseudo: []uint16{}},
+		},
+		{
+			"set rgb",
+			func(led *lightswarm.LED) { led.SetRGB(85, 199, 237) },
+			0,
+			State{RGB: [3]byte{85, 199, 237}, Pseudo: []uint16{}},
+		},
+		{
+			"fade rgb",
+			func(led *lightswarm.LED) {
+				led.FadeRGB(lightswarm.Fade{Level: 85, Interval: 1, Step: 1}, lightswarm.Fade{Level: 199, Interval: 1, Step: 1}, lightswarm.Fade{Level: 237, Interval: 1, Step: 1})
+			},
+			lightswarm.FadeInterval * 100,
+			State{RGB: [3]byte{85, 100, 100}, Fading: true, Pseudo: []uint16{}},
+		},
+		{
+			"set psuedo addresses",
+			func(led *lightswarm.LED) { led.SetPseudoAddress(4096); led.SetPseudoAddress(10) },
+			0,
+			State{Pseudo: []uint16{10, 4096}},
+		},
+		{
+			"erase psuedo address table",
+			func(led *lightswarm.LED) { led.SetPseudoAddress(4096); led.ErasePseudoAddressTable() },
+			0,
+			State{Pseudo: []uint16{}},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			net := New(690)
+			tc.cmds(lightswarm.New(690, net))
+			net.Advance(tc.elapsed)
+			state, ok := net.State(690)
+			assert.True(t, ok)
+			assert.Equal(t, tc.expected, state)
+			assert.Empty(t, net.Errors())
+		})
+	}
+}
+
+func TestNetworkAddressing(t *testing.T) {
+	net := New(690, 227, 362)
+	lightswarm.New(690, net).On()
+	for _, addr := range []uint16{690, 227} {
+		lightswarm.New(addr, net).SetPseudoAddress(4096)
+	}
+	lightswarm.NewGroup(4096, net).SetLevel(100)
+	lightswarm.New(1, net).On() // no node at this address
+	tt := []struct {
+		addr     uint16
+		expected State
+	}{
+		{690, State{On: true, Level: 100, Pseudo: []uint16{4096}}},
+		{227, State{On: false, Level: 100, Pseudo: []uint16{4096}}},
+		{362, State{On: false, Level: 0, Pseudo: []uint16{}}},
+	}
+	for _, tc := range tt {
+		state, ok := net.State(tc.addr)
+		assert.True(t, ok)
+		assert.Equal(t, tc.expected, state)
+	}
+	_, ok := net.State(1)
+	assert.False(t, ok)
+}
+
+func TestNetworkFadeMultiple(t *testing.T) {
+	net := New(690, 227, 362)
+	lightswarm.New(362, net).SetPseudoAddress(4096)
+	lightswarm.FadeMultiple(net, 4095,
+		lightswarm.AddressFade{Addr: 690, Fade: lightswarm.Fade{Level: 255, Interval: 1, Step: 127}},
+		lightswarm.AddressFade{Addr: 4096, Fade: lightswarm.Fade{Level: 50, Interval: 1, Step: 127}},
+	)
+	net.Advance(lightswarm.FadeInterval * 3)
+	for addr, level := range map[uint16]byte{690: 255, 227: 0, 362: 50} {
+		state, _ := net.State(addr)
+		assert.Equal(t, level, state.Level)
+	}
+}
+
+func TestNetworkZeroValue(t *testing.T) {
+	net := &Network{}
+	lightswarm.New(690, net).On()
+	_, ok := net.State(690)
+	assert.False(t, ok)
+	net.Add(690)
+	lightswarm.New(690, net).On()
+	state, ok := net.State(690)
+	assert.True(t, ok)
+	assert.True(t, state.On)
+}
+
+func TestNetworkWrite(t *testing.T) {
+	tt := []struct {
+		name   string
+		writes [][]byte
+		on     bool
+		errors int
+	}{
+		{
+			"whole frame",
+			[][]byte{{lightswarm.END, 2, 178, lightswarm.ON, 144, lightswarm.END}},
+			true,
+			0,
+		},
+		{
+			"frame split across writes",
+			[][]byte{{lightswarm.END, 2, 178}, {lightswarm.ON, 144, lightswarm.END}},
+			true,
+			0,
+		},
+		{
+			"bad checksum",
+			[][]byte{{lightswarm.END, 2, 178, lightswarm.ON, 145, lightswarm.END}},
+			false,
+			1,
+		},
+		{
+			"wrong number of arguments",
+			[][]byte{lightswarm.Frame{Addr: 690, Cmd: lightswarm.SET_RGB_LEVELS, CmdArgs: []byte{1}}.Bytes()},
+			false,
+			1,
+		},
+		{
+			"unknown command",
+			[][]byte{lightswarm.Frame{Addr: 690, Cmd: 0x01}.Bytes()},
+			false,
+			1,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			net := New(690)
+			for _, w := range tc.writes {
+				n, err := net.Write(w)
+				assert.Nil(t, err)
+				assert.Equal(t, len(w), n)
+			}
+			state, _ := net.State(690)
+			assert.Equal(t, tc.on, state.On)
+			assert.Len(t, net.Errors(), tc.errors)
+		})
+	}
+}
+
+func TestNetworkNow(t *testing.T) {
+	net := New()
+	net.Advance(time.Second)
+	net.Advance(time.Second)
+	assert.Equal(t, time.Second*2, net.Now())
+}
@@ -0,0 +1,283 @@
+package lightswarm
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The target of a fade in progress
+type FadeTarget struct {
+	Level byte
+	Done  time.Time // Estimated completion time
+}
+
+// The last commanded state of an address
+type State struct {
+	On      bool
+	Level   byte           // Estimated current level
+	RGB     [3]byte        // Estimated current Red, Green and Blue levels
+	Fade    *FadeTarget    // Level fade in progress, nil when idle
+	RGBFade [3]*FadeTarget // Red, Green and Blue fades in progress
+	Updated time.Time      // Time of the last command
+}
+
+// A single tracked level, either set directly or fading
+type trackedLevel struct {
+	level   byte
+	fade    *Fade
+	started time.Time
+}
+
+// Sets the level immediately, cancelling any fade
+func (l *trackedLevel) set(level byte) {
+	l.level = level
+	l.fade = nil
+}
+
+// Starts a fade from the estimated current level
+func (l *trackedLevel) start(f Fade, now time.Time) {
+	l.level, _ = l.estimate(now)
+	l.fade = &f
+	l.started = now
+}
+
+// Returns the estimated level at the given time and the fade target if
+// the fade has not yet completed
+func (l *trackedLevel) estimate(now time.Time) (byte, *FadeTarget) {
+	if l.fade == nil {
+		return l.level, nil
+	}
+	target := &FadeTarget{
+		Level: byte(l.fade.level()),
+		Done:  l.started.Add(l.fade.Duration(l.level)),
+	}
+	if !now.Before(target.Done) {
+		return target.Level, nil
+	}
+	steps := int(now.Sub(l.started) / (time.Duration(l.fade.interval()) * FadeInterval))
+	level, change := int(l.level), steps*l.fade.step()
+	if level < int(target.Level) {
+		level += change
+	} else {
+		level -= change
+	}
+	return byte(level), target
+}
+
+// The tracked state of a single address
+type tracked struct {
+	on      bool
+	level   trackedLevel
+	rgb     [3]trackedLevel
+	updated time.Time
+}
+
+// Returns the state of the address at the given time
+func (t *tracked) state(now time.Time) State {
+	s := State{
+		On:      t.on,
+		Updated: t.updated,
+	}
+	s.Level, s.Fade = t.level.estimate(now)
+	for i := range t.rgb {
+		s.RGB[i], s.RGBFade[i] = t.rgb[i].estimate(now)
+	}
+	return s
+}
+
+// Records the last commanded state of every address written to, so the
+// state of a light can be queried without hardware read-back. Commands
+// sent to a psuedo address are fanned out to the members learnt from
+// SET_PSUEDO_ADDRESS frames written through the Tracker. Levels are
+// estimated from the time each fade started and its interval and step.
+type Tracker struct {
+	// Exported Fields
+	Writer  io.Writer // Underlying writer, may be nil to only track
+	AllCall *uint16   // Address every node receives, used by Recall
+
+	once    sync.Once
+	mu      sync.Mutex
+	buf     []byte
+	states  map[uint16]*tracked
+	members map[uint16]map[uint16]bool // psuedo address -> physical addresses
+
+	// Overridden in tests
+	now func() time.Time
+}
+
+// Creates the state maps and defaults the clock on first use, so a
+// Tracker built as a literal is usable
+func (t *Tracker) init() {
+	t.once.Do(func() {
+		t.states = map[uint16]*tracked{}
+		t.members = map[uint16]map[uint16]bool{}
+		if t.now == nil {
+			t.now = time.Now
+		}
+	})
+}
+
+// Returns the tracked state for the address, creating it if needed
+func (t *Tracker) get(addr uint16) *tracked {
+	s, ok := t.states[addr]
+	if !ok {
+		s = &tracked{}
+		t.states[addr] = s
+	}
+	return s
+}
+
+// Returns the addresses affected by a frame sent to the given address
+func (t *Tracker) targets(addr uint16) []uint16 {
+	addrs := []uint16{addr}
+	for member := range t.members[addr] {
+		addrs = append(addrs, member)
+	}
+	return addrs
+}
+
+// Applies a frame to the state of every address it affects, frames
+// which do not decode to a known command are ignored
+func (t *Tracker) apply(frame Frame, now time.Time) {
+	cmd, err := frame.Command()
+	if err != nil {
+		return
+	}
+	if c, ok := cmd.(*FadeMultipleToLevel); ok {
+		for _, af := range c.Fades {
+			for _, addr := range t.targets(af.Addr) {
+				s := t.get(addr)
+				s.level.start(af.Fade, now)
+				s.updated = now
+			}
+		}
+		return
+	}
+	for _, addr := range t.targets(frame.Addr) {
+		s := t.get(addr)
+		switch c := cmd.(type) {
+		case *On:
+			s.on = true
+		case *Off:
+			s.on = false
+		case *Toggle:
+			s.on = !s.on
+		case *SetLevel:
+			s.level.set(c.Level)
+		case *FadeToLevel:
+			s.level.start(c.Fade, now)
+		case *FadeDown:
+			if level, _ := s.level.estimate(now); c.Fade.level() < int(level) {
+				s.level.start(c.Fade, now)
+			}
+		case *SetRGBLevels:
+			s.rgb[0].set(c.R)
+			s.rgb[1].set(c.G)
+			s.rgb[2].set(c.B)
+		case *FadeRGBToLevel:
+			s.rgb[0].start(c.R, now)
+			s.rgb[1].start(c.G, now)
+			s.rgb[2].start(c.B, now)
+		case *SetPseudoAddress:
+			if _, ok := t.members[c.Addr]; !ok {
+				t.members[c.Addr] = map[uint16]bool{}
+			}
+			t.members[c.Addr][addr] = true
+		case *ErasePseudoAddressTable:
+			for _, members := range t.members {
+				delete(members, addr)
+			}
+		default:
+			continue
+		}
+		s.updated = now
+	}
+}
+
+// Records the frames in the given bytes, partial frames are buffered
+// until the rest is written and undecodable frames are ignored
+func (t *Tracker) record(p []byte) {
+	t.init()
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	for {
+		advance, token, _ := ScanFrames(t.buf, false)
+		if advance == 0 {
+			break
+		}
+		if frame, err := ParseFrame(token); token != nil && err == nil {
+			t.apply(frame, now)
+		}
+		t.buf = t.buf[advance:]
+	}
+}
+
+// Writes to the underlying writer, recording the frames once written
+func (t *Tracker) Write(p []byte) (int, error) {
+	return t.WriteContext(context.Background(), p)
+}
+
+// As Write but abandoned if the context is done before it is written
+func (t *Tracker) WriteContext(ctx context.Context, p []byte) (int, error) {
+	w := t.Writer
+	if w == nil {
+		w = ioutil.Discard
+	}
+	n, err := WriteContext(ctx, w, p)
+	if err != nil {
+		return n, err
+	}
+	t.record(p[:n])
+	return n, nil
+}
+
+// Returns the state of the given address
+func (t *Tracker) State(addr uint16) (State, bool) {
+	t.init()
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.states[addr]
+	if !ok {
+		return State{}, false
+	}
+	return s.state(now), true
+}
+
+// Returns every tracked address in ascending order
+func (t *Tracker) Addrs() []uint16 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	addrs := make([]uint16, 0, len(t.states))
+	for addr := range t.states {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	return addrs
+}
+
+// Returns the physical addresses in the given psuedo address in
+// ascending order
+func (t *Tracker) Members(pseudo uint16) []uint16 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	addrs := []uint16{}
+	for addr := range t.members[pseudo] {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	return addrs
+}
+
+// Constructs a new Tracker writing to the given writer
+func NewTracker(writer io.Writer) *Tracker {
+	return &Tracker{
+		Writer: writer,
+	}
+}
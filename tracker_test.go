@@ -0,0 +1,236 @@
+package lightswarm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Constructs a Tracker using a fake clock
+func fakeTracker() (*Tracker, *fakeClock, *bytes.Buffer) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	buff := bytes.NewBuffer(nil)
+	t := NewTracker(buff)
+	t.now = clock.now
+	return t, clock, buff
+}
+
+func TestTrackedLevelEstimate(t *testing.T) {
+	start := time.Unix(0, 0)
+	tt := []struct {
+		name     string
+		from     byte
+		fade     Fade
+		elapsed  time.Duration
+		expected byte
+		target   *FadeTarget
+	}{
+		{
+			"fade started",
+			0,
+			Fade{255, 1, 5},
+			0,
+			0,
+			&FadeTarget{255, start.Add(time.Millisecond * 510)},
+		},
+		{
+			"fade up in progress",
+			0,
+			Fade{255, 1, 5},
+			time.Millisecond * 105,
+			50,
+			&FadeTarget{255, start.Add(time.Millisecond * 510)},
+		},
+		{
+			"fade down in progress",
+			255,
+			Fade{0, 2, 5},
+			time.Millisecond * 200,
+			205,
+			&FadeTarget{0, start.Add(time.Millisecond * 1020)},
+		},
+		{
+			"fade complete",
+			0,
+			Fade{255, 1, 5},
+			time.Second,
+			255,
+			nil,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			l := &trackedLevel{level: tc.from}
+			l.start(tc.fade, start)
+			level, target := l.estimate(start.Add(tc.elapsed))
+			assert.Equal(t, tc.expected, level)
+			assert.Equal(t, tc.target, target)
+		})
+	}
+}
+
+func TestTrackerState(t *testing.T) {
+	tt := []struct {
+		name     string
+		cmds     func(led *LED)
+		elapsed  time.Duration
+		expected State
+	}{
+		{
+			"on",
+			func(led *LED) { led.On() },
+			0,
+			State{On: true},
+		},
+		{
+			"toggle twice",
+			func(led *LED) { led.Toggle(); led.Toggle() },
+			0,
+			State{On: false},
+		},
+		{
+			"set level",
+			func(led *LED) { led.SetLevel(128) },
+			0,
+			State{Level: 128},
+		},
+		{
+			"fade in progress",
+			func(led *LED) { led.Fade(Fade{255, 1, 5}) },
+			time.Millisecond * 100,
+			State{Level: 50, Fade: &FadeTarget{255, time.Unix(0, 0).Add(time.Millisecond * 510)}},
+		},
+		{
+			"fade complete",
+			func(led *LED) { led.Fade(Fade{255, 1, 5}) },
+			time.Second,
+			State{Level: 255},
+		},
+		{
+			"legacy fade down ignores higher level",
+			func(led *LED) { led.FadeDown(Fade{255, 1, 5}) },
+			time.Second,
+			State{Level: 0},
+		},
+		{
+			"set rgb",
+			func(led *LED) { led.SetRGB(85, 199, 237) },
+			0,
+			State{RGB: [3]byte{85, 199, 237}},
+		},
+		{
+			"fade rgb complete",
+			func(led *LED) { led.FadeRGB(Fade{85, 1, 1}, Fade{199, 1, 1}, Fade{237, 1, 1}) },
+			time.Second * 3,
+			State{RGB: [3]byte{85, 199, 237}},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker, clock, _ := fakeTracker()
+			tc.cmds(New(690, tracker))
+			clock.t = clock.t.Add(tc.elapsed)
+			state, ok := tracker.State(690)
+			assert.True(t, ok)
+			tc.expected.Updated = time.Unix(0, 0)
+			assert.Equal(t, tc.expected, state)
+		})
+	}
+}
+
+func TestTrackerPseudoAddresses(t *testing.T) {
+	tracker, _, _ := fakeTracker()
+	for _, addr := range []uint16{690, 227} {
+		New(addr, tracker).SetPseudoAddress(4096)
+	}
+	New(362, tracker).On()
+	assert.Equal(t, []uint16{227, 690}, tracker.Members(4096))
+	NewGroup(4096, tracker).SetLevel(100)
+	New(227, tracker).ErasePseudoAddressTable()
+	NewGroup(4096, tracker).On()
+	assert.Equal(t, []uint16{690}, tracker.Members(4096))
+	assert.Equal(t, []uint16{227, 362, 690, 4096}, tracker.Addrs())
+	tt := []struct {
+		addr  uint16
+		on    bool
+		level byte
+	}{
+		{690, true, 100},
+		{227, false, 100},
+		{362, true, 0},
+		{4096, true, 100},
+	}
+	for _, tc := range tt {
+		state, ok := tracker.State(tc.addr)
+		assert.True(t, ok)
+		assert.Equal(t, tc.on, state.On, "address %d", tc.addr)
+		assert.Equal(t, tc.level, state.Level, "address %d", tc.addr)
+	}
+	_, ok := tracker.State(1)
+	assert.False(t, ok)
+}
+
+func TestTrackerFadeMultiple(t *testing.T) {
+	tracker, clock, _ := fakeTracker()
+	New(362, tracker).SetPseudoAddress(4096)
+	FadeMultiple(tracker, 4095, AddressFade{690, Fade{255, 1, 127}}, AddressFade{4096, Fade{50, 1, 127}})
+	clock.t = clock.t.Add(time.Second)
+	for addr, level := range map[uint16]byte{690: 255, 362: 50} {
+		state, _ := tracker.State(addr)
+		assert.Equal(t, level, state.Level)
+	}
+}
+
+func TestTrackerWrite(t *testing.T) {
+	tracker, _, buff := fakeTracker()
+	on := Frame{690, ON, nil}.Bytes()
+	// partial frames are buffered until complete
+	n, err := tracker.Write(on[:3])
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+	_, ok := tracker.State(690)
+	assert.False(t, ok)
+	tracker.Write(on[3:])
+	state, _ := tracker.State(690)
+	assert.True(t, state.On)
+	assert.Equal(t, on, buff.Bytes())
+	// bad frames are ignored
+	tracker.Write([]byte{END, 2, 178, OFF, 0, END})
+	state, _ = tracker.State(690)
+	assert.True(t, state.On)
+}
+
+func TestTrackerWriteError(t *testing.T) {
+	boom := errors.New("boom")
+	tracker := NewTracker(errWriter{boom})
+	_, _, err := New(690, tracker).On()
+	assert.Equal(t, boom, err)
+	_, ok := tracker.State(690)
+	assert.False(t, ok)
+	_, _, err = New(690, tracker).OnContext(cancelled())
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestTrackerNilWriter(t *testing.T) {
+	tracker := NewTracker(nil)
+	_, _, err := New(690, tracker).On()
+	assert.Nil(t, err)
+	state, _ := tracker.State(690)
+	assert.True(t, state.On)
+}
+
+func TestTrackerZeroValue(t *testing.T) {
+	buff := bytes.NewBuffer(nil)
+	tracker := &Tracker{Writer: buff}
+	_, _, err := New(690, tracker).On()
+	assert.Nil(t, err)
+	state, ok := tracker.State(690)
+	assert.True(t, ok)
+	assert.True(t, state.On)
+	assert.Equal(t, Frame{Addr: 690, Cmd: ON}.Bytes(), buff.Bytes())
+	assert.Empty(t, (&Tracker{}).Snapshot("empty").Targets)
+}